@@ -0,0 +1,339 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ПРИНЯТИЕ ДОКУМЕНТОВ КЛИЕНТАМИ ===
+// Клиент принимает конкретную редакцию EULA и политики конфиденциальности
+// для лицензии (и устройства, если указано):
+//   - при активации — поле accept в /api/licenses/validate;
+//   - отдельно — POST /api/licenses/accept {"key","device","accept":{"eula_text":3}}.
+// В ответе обоих — acceptance_required: действующие редакции на языке запроса,
+// ещё не принятые. Номер редакции сквозной для всех языков, поэтому принятие
+// действующей редакции на любом языке засчитывается.
+//
+// GET /api/acceptances?license_id=5&doc=eula_text — история принятий
+// GET /api/acceptances/report?doc=eula_text&all=1 — лицензии с активациями
+// (all=1 — все), держатели которых не приняли действующую редакцию
+
+// Документы, требующие принятия, и их публичные адреса
+var acceptanceDocs = []struct{ key, path string }{
+	{"eula_text", "/api/docs/eula"},
+	{"privacy_policy", "/api/docs/privacy"},
+}
+
+func isAcceptanceDoc(key string) bool {
+	for _, d := range acceptanceDocs {
+		if d.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// checkAcceptance — принимать можно только опубликованные редакции документов из acceptanceDocs;
+// отказ — localizedError (см. isInputError), остальное — ошибки БД
+func checkAcceptance(q queryRower, accept map[string]int) error {
+	for key, version := range accept {
+		if !isAcceptanceDoc(key) {
+			return newLocalizedError("accept.not_required", key)
+		}
+		v, err := docVersionByNumber(q, key, version)
+		if err != nil {
+			return err
+		}
+		if v == nil || v.Status != docPublished {
+			return newLocalizedError("accept.not_published", version, key)
+		}
+	}
+	return nil
+}
+
+// recordAcceptances сохраняет принятия внутри транзакции активации или отдельного запроса;
+// возвращает число новых записей (повторное принятие той же редакции не дублируется)
+func recordAcceptances(tx *sql.Tx, r *http.Request, licenseID int, device string, accept map[string]int) (int, error) {
+	if err := checkAcceptance(tx, accept); err != nil {
+		return 0, err
+	}
+	keys := make([]string, 0, len(accept))
+	for key := range accept {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	added := 0
+	for _, key := range keys {
+		res, err := tx.Exec(`INSERT OR IGNORE INTO document_acceptances
+			(license_id, device, doc_key, version, accepted_at, ip, user_agent)
+			VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''))`,
+			licenseID, device, key, accept[key], time.Now().UTC().Format(dumpTimeFormat), clientIP(r), r.UserAgent())
+		if err != nil {
+			return 0, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		added++
+		after := map[string]any{"doc": key, "version": accept[key], "device": device}
+		if err := recordAudit(tx, r, "document.accept", "license", strconv.Itoa(licenseID), nil, after); err != nil {
+			return 0, err
+		}
+	}
+	return added, nil
+}
+
+type pendingDoc struct {
+	Doc     string `json:"doc"`
+	Version int    `json:"version"`
+	URL     string `json:"url"`
+}
+
+// pendingAcceptance — действующие редакции на языке lang, не принятые для лицензии
+// и устройства ни на одном из языков
+func pendingAcceptance(licenseID int, device, lang string) []pendingDoc {
+	pending := []pendingDoc{}
+	for _, d := range acceptanceDocs {
+		current, err := currentDocVersion(db, d.key, lang)
+		if err != nil || current == nil {
+			continue
+		}
+		if accepted, err := hasAcceptedCurrent(licenseID, device, d.key); err != nil || !accepted {
+			pending = append(pending, pendingDoc{d.key, current.Version, fmt.Sprintf("%s?version=%d", d.path, current.Version)})
+		}
+	}
+	return pending
+}
+
+// hasAcceptedCurrent — принята ли действующая редакция документа на каком-либо языке
+func hasAcceptedCurrent(licenseID int, device, key string) (bool, error) {
+	versions, err := currentDocVersions(db, key)
+	if err != nil {
+		return false, err
+	}
+	for _, version := range versions {
+		var n int
+		err := db.QueryRow(`SELECT COUNT(*) FROM document_acceptances
+			WHERE license_id = ? AND doc_key = ? AND version = ? AND device = ?`,
+			licenseID, key, version, device).Scan(&n)
+		if err != nil || n > 0 {
+			return n > 0, err
+		}
+	}
+	return false, nil
+}
+
+// handleAccept — POST /api/licenses/accept; защищён так же, как проверка ключа
+func handleAccept(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	var input struct {
+		Key    string         `json:"key"`
+		Device string         `json:"device"`
+		Accept map[string]int `json:"accept"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": tr(r, "err.invalid_json"), "code": codeInvalidJSON})
+		return
+	}
+	if len(input.Accept) == 0 {
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": tr(r, "accept.none"), "code": codeInvalidAcceptance})
+		return
+	}
+
+	key := strings.ToUpper(strings.TrimSpace(input.Key))
+	device := strings.TrimSpace(input.Device)
+	var licenseID int
+	err := db.QueryRow("SELECT id FROM licenses WHERE UPPER(key) = ?", key).Scan(&licenseID)
+	if err == sql.ErrNoRows {
+		validateLockout.fail(clientIP(r))
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": tr(r, "validate.not_found"), "code": codeKeyNotFound})
+		return
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	added, err := recordAcceptances(tx, r, licenseID, device, input.Accept)
+	if isInputError(err) {
+		json.NewEncoder(w).Encode(map[string]any{"success": false, "error": errorText(r, err), "code": codeInvalidAcceptance})
+		return
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":             true,
+		"accepted":            added,
+		"acceptance_required": pendingAcceptance(licenseID, device, requestLang(r)),
+	})
+}
+
+// === ИСТОРИЯ И ОТЧЁТ ===
+type acceptanceRecord struct {
+	ID         int64  `json:"id"`
+	LicenseID  int    `json:"license_id"`
+	LicenseKey string `json:"license_key,omitempty"`
+	Device     string `json:"device,omitempty"`
+	Doc        string `json:"doc"`
+	Version    int    `json:"version"`
+	AcceptedAt string `json:"accepted_at"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+func handleAcceptances(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	q := r.URL.Query()
+	where := []string{"1=1"}
+	var args []any
+	if s := q.Get("license_id"); s != "" {
+		where = append(where, "a.license_id = ?")
+		args = append(args, s)
+	}
+	if s := q.Get("doc"); s != "" {
+		where = append(where, "a.doc_key = ?")
+		args = append(args, s)
+	}
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	rows, err := db.Query(`SELECT a.id, a.license_id, COALESCE(l.key, ''), a.device, a.doc_key, a.version,
+			a.accepted_at, COALESCE(a.ip, ''), COALESCE(a.user_agent, '')
+		FROM document_acceptances a LEFT JOIN licenses l ON l.id = a.license_id
+		WHERE `+strings.Join(where, " AND ")+` ORDER BY a.id DESC LIMIT `+strconv.Itoa(limit), args...)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer rows.Close()
+	list := []acceptanceRecord{}
+	for rows.Next() {
+		var a acceptanceRecord
+		if err := rows.Scan(&a.ID, &a.LicenseID, &a.LicenseKey, &a.Device, &a.Doc, &a.Version,
+			&a.AcceptedAt, &a.IP, &a.UserAgent); err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		list = append(list, a)
+	}
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleAcceptanceReport — лицензии без принятия действующей редакции (на любом языке),
+// по каждому документу
+func handleAcceptanceReport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	q := r.URL.Query()
+	doc := q.Get("doc")
+	if doc != "" && !isAcceptanceDoc(doc) {
+		httpError(w, r, "param.choice", 400, "doc", "eula_text, privacy_policy")
+		return
+	}
+	activated := "l.current_uses > 0"
+	if all, _ := strconv.ParseBool(q.Get("all")); all {
+		activated = "1=1"
+	}
+
+	type missingLicense struct {
+		ID           int    `json:"id"`
+		Key          string `json:"key"`
+		Description  string `json:"description"`
+		Supplier     string `json:"supplier,omitempty"`
+		ExpiryDate   string `json:"expiry_date"`
+		CurrentUses  int    `json:"current_uses"`
+		LastVersion  int    `json:"last_accepted_version,omitempty"`
+		LastAccepted string `json:"last_accepted_at,omitempty"`
+	}
+	type docReport struct {
+		Doc             string           `json:"doc"`
+		CurrentVersion  int              `json:"current_version"`  // на русском
+		CurrentVersions map[string]int   `json:"current_versions"` // по языкам
+		Missing         int              `json:"missing"`
+		Licenses        []missingLicense `json:"licenses"`
+	}
+
+	report := []docReport{}
+	for _, d := range acceptanceDocs {
+		if doc != "" && d.key != doc {
+			continue
+		}
+		rep := docReport{Doc: d.key, Licenses: []missingLicense{}}
+		versions, err := currentDocVersions(db, d.key)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		rep.CurrentVersions = versions
+		if len(versions) == 0 {
+			report = append(report, rep)
+			continue
+		}
+		rep.CurrentVersion = versions[defaultLang]
+		current := make([]string, 0, len(versions))
+		args := []any{d.key, d.key}
+		for _, v := range versions {
+			current = append(current, "?")
+			args = append(args, v)
+		}
+
+		rows, err := db.Query(`SELECT l.id, l.key, COALESCE(l.description, ''), COALESCE(l.supplier, ''),
+				l.expiry_date, l.current_uses, COALESCE(MAX(a.version), 0), COALESCE(MAX(a.accepted_at), '')
+			FROM licenses l LEFT JOIN document_acceptances a ON a.license_id = l.id AND a.doc_key = ?
+			WHERE `+activated+` AND NOT EXISTS (SELECT 1 FROM document_acceptances c
+				WHERE c.license_id = l.id AND c.doc_key = ? AND c.version IN (`+strings.Join(current, ", ")+`))
+			GROUP BY l.id ORDER BY l.id`, args...)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		for rows.Next() {
+			var m missingLicense
+			if err := rows.Scan(&m.ID, &m.Key, &m.Description, &m.Supplier, &m.ExpiryDate, &m.CurrentUses,
+				&m.LastVersion, &m.LastAccepted); err != nil {
+				rows.Close()
+				httpError(w, r, "err.db", 500)
+				return
+			}
+			m.ExpiryDate = exportDate(m.ExpiryDate)
+			rep.Licenses = append(rep.Licenses, m)
+		}
+		rows.Close()
+		rep.Missing = len(rep.Licenses)
+		report = append(report, rep)
+	}
+	json.NewEncoder(w).Encode(report)
+}
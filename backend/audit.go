@@ -0,0 +1,341 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ЖУРНАЛ АУДИТА ===
+// Каждое изменение пишет событие в audit_events в той же транзакции, что и
+// само изменение. События связаны в цепочку: hash = sha256(prev_hash + поля),
+// поэтому удаление или правка записи задним числом видна в /api/audit/verify.
+// Последние записи цепочка сама не защищает — их удаление она бы не заметила,
+// поэтому id и хэш головы хранятся в meta.audit_head и сверяются при проверке.
+
+type auditEvent struct {
+	ID         int64           `json:"id"`
+	At         string          `json:"at"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityID   string          `json:"entity_id"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Diff       json.RawMessage `json:"diff"`
+	IP         string          `json:"ip"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+}
+
+// Фиксированная ширина, чтобы фильтр по времени работал сравнением строк
+const auditTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+// queryRower — общее у *sql.DB и *sql.Tx для чтения снимков «до/после»
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// auditActor — кто выполняет изменение. Авторизации пока нет, а заголовку
+// от клиента верить нельзя, поэтому записывается только то, что проверил
+// сервер: CN проверенного клиентского сертификата (mTLS) или IP клиента.
+func auditActor(r *http.Request) string {
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		if cn := r.TLS.VerifiedChains[0][0].Subject.CommonName; cn != "" {
+			return "cert:" + cn
+		}
+	}
+	return clientIP(r)
+}
+
+func (e *auditEvent) computeHash() string {
+	h := sha256.New()
+	for _, part := range []string{
+		e.PrevHash, e.At, e.Actor, e.Action, e.EntityType, e.EntityID,
+		string(e.Before), string(e.After), string(e.Diff), e.IP,
+	} {
+		h.Write([]byte(strconv.Itoa(len(part))))
+		h.Write([]byte{':'})
+		h.Write([]byte(part))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func marshalAudit(v any) json.RawMessage {
+	if v == nil {
+		return json.RawMessage("null")
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+// auditDiff — изменившиеся поля верхнего уровня: {"поле": {"before": ..., "after": ...}}
+func auditDiff(before, after json.RawMessage) json.RawMessage {
+	var b, a map[string]any
+	json.Unmarshal(before, &b)
+	json.Unmarshal(after, &a)
+
+	diff := map[string]map[string]any{}
+	for k, bv := range b {
+		if av, ok := a[k]; !ok || !reflect.DeepEqual(av, bv) {
+			diff[k] = map[string]any{"before": bv, "after": a[k]}
+		}
+	}
+	for k, av := range a {
+		if _, ok := b[k]; !ok {
+			diff[k] = map[string]any{"before": nil, "after": av}
+		}
+	}
+	return marshalAudit(diff)
+}
+
+// recordAudit добавляет событие в цепочку внутри транзакции изменения
+func recordAudit(tx *sql.Tx, r *http.Request, action, entityType, entityID string, before, after any) error {
+	e := auditEvent{
+		At:         time.Now().UTC().Format(auditTimeFormat),
+		Actor:      auditActor(r),
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     marshalAudit(before),
+		After:      marshalAudit(after),
+		IP:         clientIP(r),
+	}
+	e.Diff = auditDiff(e.Before, e.After)
+
+	err := tx.QueryRow("SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&e.PrevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	e.Hash = e.computeHash()
+
+	res, err := tx.Exec(`INSERT INTO audit_events
+		(at, actor, action, entity_type, entity_id, before_json, after_json, diff_json, ip, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.At, e.Actor, e.Action, e.EntityType, e.EntityID,
+		string(e.Before), string(e.After), string(e.Diff), e.IP, e.PrevHash, e.Hash)
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("INSERT OR REPLACE INTO meta (key, value) VALUES ('audit_head', ?)", auditHead(e.ID, e.Hash))
+	return err
+}
+
+// auditHead — значение meta.audit_head: «id:hash» последнего события
+func auditHead(id int64, hash string) string {
+	return strconv.FormatInt(id, 10) + ":" + hash
+}
+
+// initAuditHead запоминает голову цепочки в базах, где её ещё не хранили
+func initAuditHead() error {
+	_, err := db.Exec(`INSERT OR IGNORE INTO meta (key, value)
+		SELECT 'audit_head', id || ':' || hash FROM audit_events ORDER BY id DESC LIMIT 1`)
+	return err
+}
+
+// licenseSnapshot — состояние лицензии для before/after; nil, если её нет
+func licenseSnapshot(q queryRower, id any) (*License, error) {
+	var l License
+	err := q.QueryRow(`SELECT
+			id, key, description, expiry_date, max_uses, current_uses, created_at,
+			COALESCE(cost, 0), COALESCE(supplier, ''), COALESCE(activated_on, ''), COALESCE(product, '')
+		FROM licenses WHERE id = ?`, id).
+		Scan(&l.ID, &l.Key, &l.Description, &l.ExpiryDate, &l.MaxUses, &l.CurrentUses, &l.CreatedAt,
+			&l.Cost, &l.Supplier, &l.ActivatedOn, &l.Product)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+// auditLicenseCreated пишет событие о только что вставленной лицензии
+func auditLicenseCreated(tx *sql.Tx, r *http.Request, res sql.Result, action string) error {
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+	after, err := licenseSnapshot(tx, id)
+	if err != nil {
+		return err
+	}
+	return recordAudit(tx, r, action, "license", strconv.FormatInt(id, 10), nil, after)
+}
+
+const auditColumns = `id, at, actor, action, entity_type, entity_id,
+	before_json, after_json, diff_json, COALESCE(ip, ''), prev_hash, hash`
+
+func scanAuditEvent(rows *sql.Rows) (auditEvent, error) {
+	var e auditEvent
+	var before, after, diff string
+	err := rows.Scan(&e.ID, &e.At, &e.Actor, &e.Action, &e.EntityType, &e.EntityID,
+		&before, &after, &diff, &e.IP, &e.PrevHash, &e.Hash)
+	e.Before, e.After, e.Diff = json.RawMessage(before), json.RawMessage(after), json.RawMessage(diff)
+	return e, err
+}
+
+// === ЗАПРОС ЖУРНАЛА ===
+// GET /api/audit?entity_type=license&entity_id=5&actor=...&action=...&from=2025-01-01&to=2025-12-31&limit=100&offset=0
+func handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	where := []string{"1=1"}
+	var args []any
+	for _, f := range []struct{ param, column string }{
+		{"entity_type", "entity_type"},
+		{"entity_id", "entity_id"},
+		{"actor", "actor"},
+		{"action", "action"},
+	} {
+		if v := q.Get(f.param); v != "" {
+			where = append(where, f.column+" = ?")
+			args = append(args, v)
+		}
+	}
+	if v := q.Get("from"); v != "" {
+		t, err := parseAuditTime(v, false)
+		if err != nil {
+			httpError(w, r, "param.invalid", 400, "from")
+			return
+		}
+		where = append(where, "at >= ?")
+		args = append(args, t)
+	}
+	if v := q.Get("to"); v != "" {
+		t, err := parseAuditTime(v, true)
+		if err != nil {
+			httpError(w, r, "param.invalid", 400, "to")
+			return
+		}
+		where = append(where, "at <= ?")
+		args = append(args, t)
+	}
+
+	limit, _ := strconv.Atoi(q.Get("limit"))
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	offset, _ := strconv.Atoi(q.Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	args = append(args, limit, offset)
+
+	rows, err := db.Query("SELECT "+auditColumns+" FROM audit_events WHERE "+strings.Join(where, " AND ")+
+		" ORDER BY id DESC LIMIT ? OFFSET ?", args...)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer rows.Close()
+
+	events := []auditEvent{}
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	json.NewEncoder(w).Encode(events)
+}
+
+// parseAuditTime принимает дату (2006-01-02) или RFC3339; для to дата
+// означает конец дня. Результат — строка в формате колонки at.
+func parseAuditTime(v string, endOfDay bool) (string, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.UTC().Format(auditTimeFormat), nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return "", err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Microsecond)
+	}
+	return t.UTC().Format(auditTimeFormat), nil
+}
+
+// === ПРОВЕРКА ЦЕПОЧКИ ===
+// GET /api/audit/verify — пересчитывает хэши от первой записи до последней
+// и сверяет последнюю с сохранённой головой
+func handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+
+	rows, err := db.Query("SELECT " + auditColumns + " FROM audit_events ORDER BY id")
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer rows.Close()
+
+	var head string
+	err = db.QueryRow("SELECT value FROM meta WHERE key = 'audit_head'").Scan(&head)
+	if err != nil && err != sql.ErrNoRows {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+
+	checked := 0
+	prev := ""
+	var lastID int64
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		reason := ""
+		switch {
+		case e.PrevHash != prev:
+			reason = tr(r, "audit.verify.prev_hash")
+		case e.computeHash() != e.Hash:
+			reason = tr(r, "audit.verify.hash")
+		}
+		if reason != "" {
+			json.NewEncoder(w).Encode(map[string]any{
+				"ok": false, "checked": checked, "broken_at": e.ID, "reason": reason,
+			})
+			return
+		}
+		prev, lastID = e.Hash, e.ID
+		checked++
+	}
+	if err := rows.Err(); err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if head != "" && head != auditHead(lastID, prev) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"ok": false, "checked": checked, "broken_at": lastID, "head": head,
+			"reason": tr(r, "audit.verify.truncated"),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"ok": true, "checked": checked, "head": prev})
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === Конфигурация сервера (переменные окружения LM_*) ===
+type Config struct {
+	Addr   string // LM_ADDR, по умолчанию ":8080"
+	DBPath string // LM_DB_PATH, по умолчанию "./licenses.db"
+
+	ReadTimeout       time.Duration // LM_READ_TIMEOUT
+	ReadHeaderTimeout time.Duration // LM_READ_HEADER_TIMEOUT
+	WriteTimeout      time.Duration // LM_WRITE_TIMEOUT
+	IdleTimeout       time.Duration // LM_IDLE_TIMEOUT
+	MaxHeaderBytes    int           // LM_MAX_HEADER_BYTES
+	ShutdownTimeout   time.Duration // LM_SHUTDOWN_TIMEOUT — сколько ждать запросы и фоновые задачи при остановке
+
+	// TLS включается, если заданы оба пути LM_TLS_CERT и LM_TLS_KEY
+	TLSCert           string        // LM_TLS_CERT
+	TLSKey            string        // LM_TLS_KEY
+	TLSClientCA       string        // LM_TLS_CLIENT_CA — CA для клиентских сертификатов (mTLS)
+	TLSClientAuth     string        // LM_TLS_CLIENT_AUTH: off | optional | validate | require
+	TLSReloadInterval time.Duration // LM_TLS_RELOAD_INTERVAL — период проверки файлов сертификатов
+	HTTPRedirectAddr  string        // LM_HTTP_REDIRECT_ADDR — адрес HTTP-листенера, редиректящего на HTTPS
+
+	CORSOrigins       []string      // LM_CORS_ORIGINS — origin админского интерфейса, через запятую
+	CORSPublicOrigins []string      // LM_CORS_PUBLIC_ORIGINS — origin для публичной проверки ключа ("*" — любой)
+	CORSMaxAge        time.Duration // LM_CORS_MAX_AGE — кэширование preflight
+
+	// Ограничения /api/licenses/validate
+	TrustProxy          bool          // LM_TRUST_PROXY — брать IP клиента из X-Forwarded-For
+	TrustedProxyHops    int           // LM_TRUSTED_PROXY_HOPS — сколько доверенных прокси дописывают X-Forwarded-For
+	ValidateIPRate      float64       // LM_VALIDATE_IP_RATE — запросов в секунду с одного IP
+	ValidateIPBurst     int           // LM_VALIDATE_IP_BURST
+	ValidateAPIKeyRate  float64       // LM_VALIDATE_APIKEY_RATE — запросов в секунду на X-API-Key
+	ValidateAPIKeyBurst int           // LM_VALIDATE_APIKEY_BURST
+	LockoutThreshold    int           // LM_LOCKOUT_THRESHOLD — промахов KEY_NOT_FOUND за окно до блокировки
+	LockoutWindow       time.Duration // LM_LOCKOUT_WINDOW — окно подсчёта промахов
+	LockoutBase         time.Duration // LM_LOCKOUT_BASE — первая блокировка, далее удваивается
+	LockoutMax          time.Duration // LM_LOCKOUT_MAX
+
+	// Импорт лицензий
+	ImportMaxBytes  int64         // LM_IMPORT_MAX_BYTES — предельный размер загружаемого файла
+	ImportBatchSize int           // LM_IMPORT_BATCH_SIZE — строк на транзакцию в режиме partial и предел для atomic
+	ImportTimeout   time.Duration // LM_IMPORT_TIMEOUT — дедлайн чтения/записи для запроса импорта
+}
+
+func (c Config) TLSEnabled() bool {
+	return c.TLSCert != "" && c.TLSKey != ""
+}
+
+// validate отклоняет сочетания настроек, с которыми сервер запустится, но
+// работать не будет: например, validate без TLS отклонял бы каждую проверку ключа
+func (c Config) validate() error {
+	if c.TLSClientAuth != clientAuthOff && !c.TLSEnabled() {
+		return fmt.Errorf("LM_TLS_CLIENT_AUTH=%s требует TLS: задайте LM_TLS_CERT и LM_TLS_KEY", c.TLSClientAuth)
+	}
+	return nil
+}
+
+var cfg Config
+
+func loadConfig() Config {
+	return Config{
+		Addr:   envString("LM_ADDR", ":8080"),
+		DBPath: envString("LM_DB_PATH", "./licenses.db"),
+
+		ReadTimeout:       envDuration("LM_READ_TIMEOUT", 30*time.Second),
+		ReadHeaderTimeout: envDuration("LM_READ_HEADER_TIMEOUT", 10*time.Second),
+		WriteTimeout:      envDuration("LM_WRITE_TIMEOUT", 60*time.Second),
+		IdleTimeout:       envDuration("LM_IDLE_TIMEOUT", 120*time.Second),
+		MaxHeaderBytes:    envInt("LM_MAX_HEADER_BYTES", 1<<20),
+		ShutdownTimeout:   envDuration("LM_SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		TLSCert:           envString("LM_TLS_CERT", ""),
+		TLSKey:            envString("LM_TLS_KEY", ""),
+		TLSClientCA:       envString("LM_TLS_CLIENT_CA", ""),
+		TLSClientAuth:     envString("LM_TLS_CLIENT_AUTH", clientAuthOff),
+		TLSReloadInterval: envDuration("LM_TLS_RELOAD_INTERVAL", 30*time.Second),
+		HTTPRedirectAddr:  envString("LM_HTTP_REDIRECT_ADDR", ""),
+
+		CORSOrigins:       envList("LM_CORS_ORIGINS", []string{"http://localhost:5173"}),
+		CORSPublicOrigins: envList("LM_CORS_PUBLIC_ORIGINS", []string{"*"}),
+		CORSMaxAge:        envDuration("LM_CORS_MAX_AGE", 10*time.Minute),
+
+		TrustProxy:          envBool("LM_TRUST_PROXY", false),
+		TrustedProxyHops:    envInt("LM_TRUSTED_PROXY_HOPS", 1),
+		ValidateIPRate:      envFloat("LM_VALIDATE_IP_RATE", 1),
+		ValidateIPBurst:     envInt("LM_VALIDATE_IP_BURST", 10),
+		ValidateAPIKeyRate:  envFloat("LM_VALIDATE_APIKEY_RATE", 5),
+		ValidateAPIKeyBurst: envInt("LM_VALIDATE_APIKEY_BURST", 20),
+		LockoutThreshold:    envInt("LM_LOCKOUT_THRESHOLD", 5),
+		LockoutWindow:       envDuration("LM_LOCKOUT_WINDOW", 15*time.Minute),
+		LockoutBase:         envDuration("LM_LOCKOUT_BASE", time.Minute),
+		LockoutMax:          envDuration("LM_LOCKOUT_MAX", time.Hour),
+
+		ImportMaxBytes:  int64(envInt("LM_IMPORT_MAX_BYTES", 100<<20)),
+		ImportBatchSize: envInt("LM_IMPORT_BATCH_SIZE", 500),
+		ImportTimeout:   envDuration("LM_IMPORT_TIMEOUT", 10*time.Minute),
+	}
+}
+
+func envString(key, def string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return def
+}
+
+func envInt(key string, def int) int {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %d", key, v, def)
+		return def
+	}
+	return n
+}
+
+func envFloat(key string, def float64) float64 {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %g", key, v, def)
+		return def
+	}
+	return f
+}
+
+func envBool(key string, def bool) bool {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %t", key, v, def)
+		return def
+	}
+	return b
+}
+
+func envDuration(key string, def time.Duration) time.Duration {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Некорректное значение %s=%q, используем %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+// envList читает список через запятую; пустые элементы отбрасываются
+func envList(key string, def []string) []string {
+	v := strings.TrimSpace(os.Getenv(key))
+	if v == "" {
+		return def
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
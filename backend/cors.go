@@ -0,0 +1,164 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === CORS ===
+// Политика задаётся на группу маршрутов: публичная проверка ключа доступна
+// установщикам с любых страниц, админский API — только с доверенных origin.
+type corsPolicy struct {
+	Origins     []string // разрешённые origin; "*" — любой
+	Credentials bool     // Access-Control-Allow-Credentials (cookie-сессии)
+	Methods     []string
+	Headers     []string
+	MaxAge      time.Duration
+	// Strict — отклонять не-preflight запросы с чужим Origin. Простой
+	// POST (text/plain, form) браузер отправляет без preflight, и без этой
+	// проверки изменение всё равно выполнится, даже если ответ не прочитать.
+	Strict bool
+}
+
+// Маршруты с публичной CORS-политикой (установщики и клиентские программы);
+// всё остальное — админский API
+var corsPublicRoutes = map[string]bool{
+	"/api/licenses/validate": true,
+	"/api/licenses/accept":   true,
+}
+
+// Опубликованные документы (EULA, политика, оферта) показывают сайты
+// клиентов и установщики; таблица рабочих мест EULA остаётся админской
+var corsPublicDocRoutes = map[string]bool{
+	"/api/docs/eula":    true,
+	"/api/docs/privacy": true,
+	"/api/docs/offer":   true,
+	"/api/docs/payment": true,
+	"/api/docs/invoice": true,
+}
+
+func publicCORSPolicy(c Config) corsPolicy {
+	return corsPolicy{
+		Origins: c.CORSPublicOrigins,
+		Methods: []string{"POST", "OPTIONS"},
+		Headers: []string{"Content-Type", "X-API-Key"}, // X-API-Key — лимит на ключ интегратора
+		MaxAge:  c.CORSMaxAge,
+	}
+}
+
+func publicDocsCORSPolicy(c Config) corsPolicy {
+	return corsPolicy{
+		Origins: c.CORSPublicOrigins,
+		Methods: []string{"GET", "OPTIONS"},
+		Headers: []string{"Content-Type"},
+		MaxAge:  c.CORSMaxAge,
+	}
+}
+
+func adminCORSPolicy(c Config) corsPolicy {
+	return corsPolicy{
+		Origins:     c.CORSOrigins,
+		Credentials: true,
+		Methods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		Headers:     []string{"Content-Type"},
+		MaxAge:      c.CORSMaxAge,
+		Strict:      true,
+	}
+}
+
+func (p corsPolicy) allowsOrigin(origin string) bool {
+	for _, o := range p.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p corsPolicy) wildcard() bool {
+	for _, o := range p.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// sameOrigin — запрос пришёл со страницы, отданной этим же сервером
+func sameOrigin(r *http.Request, origin string) bool {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return strings.EqualFold(origin, scheme+"://"+r.Host)
+}
+
+func (p corsPolicy) handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h := w.Header()
+		// Ответ зависит от Origin — кэши не должны отдавать его другим сайтам
+		h.Add("Vary", "Origin")
+
+		origin := r.Header.Get("Origin")
+		preflight := r.Method == "OPTIONS" && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !p.allowsOrigin(origin) {
+			if preflight || (p.Strict && !sameOrigin(r, origin)) {
+				httpError(w, r, "err.origin", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// С credentials браузер не принимает "*" — отражаем конкретный origin
+		if p.wildcard() && !p.Credentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if p.Credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			h.Set("Access-Control-Allow-Methods", strings.Join(p.Methods, ", "))
+			h.Set("Access-Control-Allow-Headers", strings.Join(p.Headers, ", "))
+			if p.MaxAge > 0 {
+				h.Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// corsMiddleware выбирает политику по пути запроса
+func corsMiddleware(next http.Handler) http.Handler {
+	public := publicCORSPolicy(cfg).handler(next)
+	docs := publicDocsCORSPolicy(cfg).handler(next)
+	admin := adminCORSPolicy(cfg).handler(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case corsPublicRoutes[r.URL.Path]:
+			public.ServeHTTP(w, r)
+		case corsPublicDocRoutes[r.URL.Path]:
+			docs.ServeHTTP(w, r)
+		default:
+			admin.ServeHTTP(w, r)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testAdminPolicy = corsPolicy{
+	Origins:     []string{"https://admin.example.com"},
+	Credentials: true,
+	Methods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+	Headers:     []string{"Content-Type"},
+	MaxAge:      10 * time.Minute,
+	Strict:      true,
+}
+
+var testPublicPolicy = corsPolicy{
+	Origins: []string{"*"},
+	Methods: []string{"POST", "OPTIONS"},
+	Headers: []string{"Content-Type"},
+}
+
+// serveCORS прогоняет запрос через политику; next отмечает, дошёл ли он до обработчика
+func serveCORS(p corsPolicy, r *http.Request) (*httptest.ResponseRecorder, bool) {
+	reached := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	})
+	w := httptest.NewRecorder()
+	p.handler(next).ServeHTTP(w, r)
+	return w, reached
+}
+
+func preflight(path, origin string) *http.Request {
+	r := httptest.NewRequest("OPTIONS", "http://api.example.com"+path, nil)
+	r.Header.Set("Origin", origin)
+	r.Header.Set("Access-Control-Request-Method", "POST")
+	r.Header.Set("Access-Control-Request-Headers", "content-type")
+	return r
+}
+
+func TestPreflightAllowedOrigin(t *testing.T) {
+	w, reached := serveCORS(testAdminPolicy, preflight("/api/licenses", "https://admin.example.com"))
+	if reached {
+		t.Fatal("preflight не должен доходить до обработчика")
+	}
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("код %d, ожидался 204", w.Code)
+	}
+	h := w.Header()
+	if got := h.Get("Access-Control-Allow-Methods"); got != "GET, POST, PUT, DELETE, OPTIONS" {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if got := h.Get("Access-Control-Allow-Headers"); got != "Content-Type" {
+		t.Errorf("Allow-Headers = %q", got)
+	}
+	if got := h.Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Max-Age = %q", got)
+	}
+}
+
+func TestPreflightDisallowedOrigin(t *testing.T) {
+	w, reached := serveCORS(testAdminPolicy, preflight("/api/licenses", "https://evil.example.com"))
+	if reached {
+		t.Fatal("preflight с чужого origin дошёл до обработчика")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("код %d, ожидался 403", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Allow-Origin = %q для чужого origin", got)
+	}
+}
+
+func TestCORSVary(t *testing.T) {
+	w, _ := serveCORS(testAdminPolicy, preflight("/api/licenses", "https://admin.example.com"))
+	vary := strings.Join(w.Header().Values("Vary"), ", ")
+	for _, want := range []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"} {
+		if !strings.Contains(vary, want) {
+			t.Errorf("Vary = %q, нет %s", vary, want)
+		}
+	}
+
+	// Обычный запрос без Origin тоже зависит от Origin для кэшей
+	w, _ = serveCORS(testAdminPolicy, httptest.NewRequest("GET", "http://api.example.com/api/licenses", nil))
+	if got := w.Header().Values("Vary"); len(got) != 1 || got[0] != "Origin" {
+		t.Errorf("Vary = %q, ожидался только Origin", got)
+	}
+}
+
+func TestCORSCredentialsReflectOrigin(t *testing.T) {
+	wildcard := testAdminPolicy
+	wildcard.Origins = []string{"*"}
+
+	r := httptest.NewRequest("GET", "http://api.example.com/api/licenses", nil)
+	r.Header.Set("Origin", "https://admin.example.com")
+	w, reached := serveCORS(wildcard, r)
+	if !reached {
+		t.Fatal("запрос с разрешённого origin не дошёл до обработчика")
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://admin.example.com" {
+		t.Errorf("Allow-Origin = %q, с credentials должен отражаться origin", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Allow-Credentials = %q", got)
+	}
+
+	// Без credentials "*" отдаётся как есть
+	r = httptest.NewRequest("POST", "http://api.example.com/api/licenses/validate", nil)
+	r.Header.Set("Origin", "https://shop.example.org")
+	w, _ = serveCORS(testPublicPolicy, r)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, ожидался *", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Allow-Credentials = %q у публичной политики", got)
+	}
+}
+
+func TestStrictRejectsForeignSimpleRequest(t *testing.T) {
+	r := httptest.NewRequest("POST", "http://api.example.com/api/licenses", strings.NewReader("key=x"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Origin", "https://evil.example.com")
+	w, reached := serveCORS(testAdminPolicy, r)
+	if reached {
+		t.Fatal("простой POST с чужого origin выполнился")
+	}
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("код %d, ожидался 403", w.Code)
+	}
+
+	// Страница, отданная этим же сервером, проходит, даже если её origin не в списке
+	r = httptest.NewRequest("POST", "http://api.example.com/api/licenses", nil)
+	r.Header.Set("Origin", "http://api.example.com")
+	if _, reached := serveCORS(testAdminPolicy, r); !reached {
+		t.Error("запрос с того же origin отклонён")
+	}
+
+	// Нестрогая политика пропускает запрос, но без CORS-заголовков
+	public := testPublicPolicy
+	public.Origins = []string{"https://shop.example.org"}
+	r = httptest.NewRequest("POST", "http://api.example.com/api/licenses/validate", nil)
+	r.Header.Set("Origin", "https://evil.example.com")
+	w, reached = serveCORS(public, r)
+	if !reached || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("нестрогая политика: reached=%v, Allow-Origin=%q", reached, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+}
+
+func TestPublicPolicyAllowsAPIKey(t *testing.T) {
+	p := publicCORSPolicy(Config{CORSPublicOrigins: []string{"*"}})
+	r := preflight("/api/licenses/validate", "https://shop.example.org")
+	r.Header.Set("Access-Control-Request-Headers", "content-type, x-api-key")
+	w, _ := serveCORS(p, r)
+	if got := w.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(got, "X-API-Key") {
+		t.Errorf("Allow-Headers = %q, нет X-API-Key", got)
+	}
+}
+
+func TestCORSMiddlewareRoutes(t *testing.T) {
+	saved := cfg
+	defer func() { cfg = saved }()
+	cfg.CORSOrigins = []string{"https://admin.example.com"}
+	cfg.CORSPublicOrigins = []string{"*"}
+
+	handler := corsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for path, want := range map[string]int{
+		"/api/licenses/validate": http.StatusNoContent,
+		"/api/licenses/accept":   http.StatusNoContent,
+		"/api/docs/eula":         http.StatusNoContent,
+		"/api/docs/offer":        http.StatusNoContent,
+		"/api/docs/eula-table":   http.StatusForbidden,
+		"/api/licenses":          http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, preflight(path, "https://shop.example.org"))
+		if w.Code != want {
+			t.Errorf("%s: код %d, ожидался %d", path, w.Code, want)
+		}
+	}
+}
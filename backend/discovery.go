@@ -0,0 +1,280 @@
+package main
+
+import (
+	_ "embed"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// === ОБНАРУЖЕНИЕ УСТРОЙСТВ В СЕТИ: РАЗБОР ФАЙЛОВ ===
+// Вместо ввода MAC по одному на схеме — то, что уже знает сеть:
+//   dhcpd    — dhcpd.leases ISC DHCP: блоки lease {...} с hardware ethernet
+//              и client-hostname; только активные (binding state active)
+//              и не истёкшие (ends) аренды
+//   dnsmasq  — dnsmasq.leases: «срок MAC IP имя client-id», имя * — нет имени
+//   ip-neigh — вывод `ip neigh`: «IP dev eth0 lladdr MAC REACHABLE»
+//   arp      — `arp -a` Linux и Windows, /proc/net/arp
+// Без format формат определяется по содержимому. Из записи берутся MAC, IP
+// и имя хоста, производитель — по OUI из oui.txt. Записи одного MAC
+// сливаются, непустые поля поздних перекрывают ранние: в dhcpd.leases новые
+// аренды дописываются в конец. Нулевые и групповые MAC (широковещательный,
+// multicast из ARP Windows, незавершённые записи /proc/net/arp) молча
+// пропускаются — это не устройства.
+//
+// Что делать с найденным — discovery_api.go.
+
+const (
+	discoveryDHCPD   = "dhcpd"
+	discoveryDnsmasq = "dnsmasq"
+	discoveryIPNeigh = "ip-neigh"
+	discoveryARP     = "arp"
+)
+
+var discoveryFormats = []string{discoveryDHCPD, discoveryDnsmasq, discoveryIPNeigh, discoveryARP}
+
+// discoveredHost — устройство из файла
+type discoveredHost struct {
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Vendor   string `json:"vendor"`
+}
+
+// discoverySkip — строка, из которой не удалось взять устройство
+type discoverySkip struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type discoveryResult struct {
+	Format  string
+	Hosts   []discoveredHost
+	Skipped []discoverySkip
+}
+
+var (
+	macTokenPattern  = regexp.MustCompile(`(?i)\b([0-9A-F]{2}[:-]){5}[0-9A-F]{2}\b|\b[0-9A-F]{4}\.[0-9A-F]{4}\.[0-9A-F]{4}\b`)
+	ipv4Pattern      = regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`)
+	dhcpdLeaseStart  = regexp.MustCompile(`^lease\s+(\S+)\s*\{`)
+	arpHostnameStart = regexp.MustCompile(`^(\S+)\s+\((\d{1,3}(?:\.\d{1,3}){3})\)\s+at\s`)
+)
+
+// detectDiscoveryFormat — формат по содержимому; "" — не распознан
+func detectDiscoveryFormat(lines []string) string {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		switch {
+		case dhcpdLeaseStart.MatchString(line):
+			return discoveryDHCPD
+		case strings.Contains(line, " lladdr ") || strings.HasSuffix(line, " FAILED") || strings.HasSuffix(line, " INCOMPLETE"):
+			return discoveryIPNeigh
+		}
+	}
+	for _, line := range lines {
+		f := strings.Fields(line)
+		if len(f) >= 4 && isDigits(f[0]) && macTokenPattern.MatchString(f[1]) && ipv4Pattern.MatchString(f[2]) {
+			return discoveryDnsmasq
+		}
+	}
+	for _, line := range lines {
+		if macTokenPattern.MatchString(line) {
+			return discoveryARP
+		}
+	}
+	return ""
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// dhcpdLease — блок lease {...} из dhcpd.leases
+type dhcpdLease struct {
+	line                     int
+	ip, mac, hostname, state string
+	ends                     time.Time // нулевое — бессрочная
+}
+
+// parseDhcpdTime — «4 2026/10/19 12:00:00» (UTC), «epoch 1792411200» или «never»
+func parseDhcpdTime(v string) time.Time {
+	f := strings.Fields(v)
+	switch {
+	case len(f) >= 2 && f[0] == "epoch":
+		if sec, err := strconv.ParseInt(f[1], 10, 64); err == nil {
+			return time.Unix(sec, 0)
+		}
+	case len(f) >= 3:
+		if t, err := time.Parse("2006/01/02 15:04:05", f[1]+" "+f[2]); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseDiscovery разбирает файл; format "" — определить по содержимому
+func parseDiscovery(data, format, lang string) (*discoveryResult, error) {
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	if format == "" {
+		format = detectDiscoveryFormat(lines)
+		if format == "" {
+			return nil, newLocalizedError("discovery.unknown_format")
+		}
+	}
+	res := &discoveryResult{Format: format, Hosts: []discoveredHost{}, Skipped: []discoverySkip{}}
+	index := map[string]int{}
+	add := func(lineNo int, rawMAC, ip, hostname string) {
+		mac, err := normalizeMAC(rawMAC)
+		if err != nil {
+			// Синтаксически верный, но нулевой или групповой — не устройство
+			if !macTokenPattern.MatchString(rawMAC) {
+				res.Skipped = append(res.Skipped, discoverySkip{lineNo, msg(lang, "discovery.bad_mac", rawMAC)})
+			}
+			return
+		}
+		if mac == "" {
+			return
+		}
+		if hostname == "*" {
+			hostname = ""
+		}
+		h := discoveredHost{MAC: mac, IP: ip, Hostname: hostname, Vendor: macVendor(mac)}
+		if i, ok := index[mac]; ok {
+			old := &res.Hosts[i]
+			if h.IP != "" {
+				old.IP = h.IP
+			}
+			if h.Hostname != "" {
+				old.Hostname = h.Hostname
+			}
+			return
+		}
+		index[mac] = len(res.Hosts)
+		res.Hosts = append(res.Hosts, h)
+	}
+
+	switch format {
+	case discoveryDHCPD:
+		// Файл хранит и свободные, истёкшие, брошенные аренды давно ушедших
+		// устройств; действует последняя запись по каждому IP, и берётся она,
+		// только если аренда активна и не закончилась
+		var leases []dhcpdLease
+		var cur *dhcpdLease
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			if m := dhcpdLeaseStart.FindStringSubmatch(line); m != nil {
+				cur = &dhcpdLease{line: i + 1, ip: m[1]}
+				continue
+			}
+			if cur == nil {
+				continue
+			}
+			line = strings.TrimSuffix(line, ";")
+			switch {
+			case strings.HasPrefix(line, "hardware ethernet "):
+				cur.mac = strings.TrimSpace(strings.TrimPrefix(line, "hardware ethernet "))
+			case strings.HasPrefix(line, "client-hostname "):
+				cur.hostname = strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "client-hostname ")), `"`)
+			case strings.HasPrefix(line, "binding state "):
+				cur.state = strings.TrimSpace(strings.TrimPrefix(line, "binding state "))
+			case strings.HasPrefix(line, "ends "):
+				cur.ends = parseDhcpdTime(strings.TrimPrefix(line, "ends "))
+			case line == "}":
+				leases = append(leases, *cur)
+				cur = nil
+			}
+		}
+		last := map[string]int{}
+		for i, l := range leases {
+			last[l.ip] = i
+		}
+		now := time.Now()
+		for i, l := range leases {
+			if last[l.ip] != i || l.state != "active" || l.mac == "" || (!l.ends.IsZero() && l.ends.Before(now)) {
+				continue
+			}
+			add(l.line, l.mac, l.ip, l.hostname)
+		}
+
+	case discoveryDnsmasq:
+		for i, line := range lines {
+			f := strings.Fields(line)
+			// duid — строка сервера DHCPv6, аренды IPv6 без MAC
+			if len(f) == 0 || f[0] == "duid" {
+				continue
+			}
+			if len(f) < 4 || !isDigits(f[0]) {
+				res.Skipped = append(res.Skipped, discoverySkip{i + 1, msg(lang, "discovery.bad_line")})
+				continue
+			}
+			if !ipv4Pattern.MatchString(f[2]) {
+				continue
+			}
+			add(i+1, f[1], f[2], f[3])
+		}
+
+	case discoveryIPNeigh:
+		for i, line := range lines {
+			f := strings.Fields(line)
+			for j := 0; j+1 < len(f); j++ {
+				if f[j] == "lladdr" && ipv4Pattern.MatchString(f[0]) {
+					add(i+1, f[j+1], f[0], "")
+					break
+				}
+			}
+		}
+
+	case discoveryARP:
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			mac := macTokenPattern.FindString(line)
+			if mac == "" {
+				continue
+			}
+			ip, hostname := ipv4Pattern.FindString(line), ""
+			// Linux arp -a: «имя (IP) at MAC [ether] on eth0», «?» — имени нет
+			if m := arpHostnameStart.FindStringSubmatch(line); m != nil {
+				ip = m[2]
+				if m[1] != "?" {
+					hostname = m[1]
+				}
+			}
+			add(i+1, mac, ip, hostname)
+		}
+	}
+	return res, nil
+}
+
+// === Производители по OUI ===
+//
+//go:embed oui.txt
+var ouiData string
+
+var (
+	ouiOnce    sync.Once
+	ouiVendors map[string]string
+)
+
+// macVendor — производитель по первым трём байтам MAC вида 00:1A:2B:...
+func macVendor(mac string) string {
+	ouiOnce.Do(func() {
+		ouiVendors = map[string]string{}
+		for _, line := range strings.Split(ouiData, "\n") {
+			prefix, vendor, ok := strings.Cut(line, "\t")
+			if ok && !strings.HasPrefix(prefix, "#") {
+				ouiVendors[strings.ToUpper(strings.TrimSpace(prefix))] = strings.TrimSpace(vendor)
+			}
+		}
+	})
+	if len(mac) < 8 {
+		return ""
+	}
+	return ouiVendors[mac[:8]]
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ОБНАРУЖЕНИЕ УСТРОЙСТВ В СЕТИ: ОЧЕРЕДЬ НА ДОБАВЛЕНИЕ ===
+// POST /api/workplaces/discovery?format=auto|dhcpd|dnsmasq|ip-neigh|arp
+//      (тело — файл или multipart file) → {"format","total","matched":[...],
+//      "proposed":[...],"dismissed":N,"skipped":[...]}
+// GET  /api/workplaces/discovery?status=pending|approved|dismissed|all
+// POST /api/workplaces/discovery/approve — {"ids":[...],"roomId":N|null,"type"?} → созданные устройства
+// POST /api/workplaces/discovery/dismiss — {"ids":[...]}
+//
+// MAC, которые уже есть на схеме, попадают в matched и ничего не меняют.
+// Остальные копятся в device_discoveries (повторная загрузка обновляет IP
+// и имя), пока администратор не добавит их в кабинет или не скроет.
+// Скрытые при следующих загрузках так и остаются скрытыми. Очередь, как
+// журнал аудита и баны, относится к экземпляру и в дамп не входит.
+
+const (
+	discoveryPending   = "pending"
+	discoveryApproved  = "approved"
+	discoveryDismissed = "dismissed"
+)
+
+type deviceDiscovery struct {
+	ID       int64  `json:"id"`
+	MAC      string `json:"mac"`
+	IP       string `json:"ip"`
+	Hostname string `json:"hostname"`
+	Vendor   string `json:"vendor"`
+	Source   string `json:"source"`
+	SeenAt   string `json:"seen_at"`
+	Status   string `json:"status"`
+	DeviceID *int64 `json:"deviceId"`
+}
+
+// discoveryMatch — найденный в файле MAC, который уже есть на схеме
+type discoveryMatch struct {
+	discoveredHost
+	DeviceID int64  `json:"deviceId"`
+	RoomID   *int64 `json:"roomId"`
+	Room     string `json:"room"`
+	Name     string `json:"name"`
+}
+
+const discoveryColumns = "id, mac, ip, hostname, vendor, source, seen_at, status, device_id"
+
+func scanDiscovery(row interface{ Scan(...any) error }) (*deviceDiscovery, error) {
+	var d deviceDiscovery
+	var deviceID sql.NullInt64
+	if err := row.Scan(&d.ID, &d.MAC, &d.IP, &d.Hostname, &d.Vendor, &d.Source, &d.SeenAt, &d.Status, &deviceID); err != nil {
+		return nil, err
+	}
+	if deviceID.Valid {
+		d.DeviceID = &deviceID.Int64
+	}
+	return &d, nil
+}
+
+func handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = discoveryPending
+		}
+		where, args := " WHERE status = ?", []any{status}
+		switch status {
+		case "all":
+			where, args = "", nil
+		case discoveryPending, discoveryApproved, discoveryDismissed:
+		default:
+			httpError(w, r, "param.choice", 400, "status", "pending, approved, dismissed, all")
+			return
+		}
+		rows, err := db.Query("SELECT "+discoveryColumns+" FROM device_discoveries"+where+" ORDER BY id", args...)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer rows.Close()
+		list := []deviceDiscovery{}
+		for rows.Next() {
+			d, err := scanDiscovery(rows)
+			if err != nil {
+				httpError(w, r, "err.db", 500)
+				return
+			}
+			list = append(list, *d)
+		}
+		writeEntity(w, 200, list)
+
+	case "POST":
+		format := r.URL.Query().Get("format")
+		if format == "auto" {
+			format = ""
+		}
+		if format != "" && !containsString(discoveryFormats, format) {
+			httpError(w, r, "param.choice", 400, "format", "auto, "+strings.Join(discoveryFormats, ", "))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, cfg.ImportMaxBytes)
+		var body io.Reader = r.Body
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			file, _, err := r.FormFile("file")
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					httpError(w, r, "err.file_too_large", http.StatusRequestEntityTooLarge, cfg.ImportMaxBytes)
+					return
+				}
+				httpError(w, r, "err.file_required", 400)
+				return
+			}
+			defer file.Close()
+			body = file
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				httpError(w, r, "err.file_too_large", http.StatusRequestEntityTooLarge, cfg.ImportMaxBytes)
+				return
+			}
+			httpError(w, r, "err.file_required", 400)
+			return
+		}
+		parsed, err := parseDiscovery(string(data), format, requestLang(r))
+		if err != nil {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		result, err := storeDiscovery(tx, parsed, requestLang(r))
+		if err == nil {
+			err = recordAudit(tx, r, "discovery.import", "discovery", parsed.Format, nil, map[string]any{
+				"format":    parsed.Format,
+				"total":     len(parsed.Hosts),
+				"matched":   len(result.Matched),
+				"proposed":  len(result.Proposed),
+				"dismissed": result.Dismissed,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		writeEntity(w, 200, map[string]any{
+			"format":    parsed.Format,
+			"total":     len(parsed.Hosts),
+			"matched":   result.Matched,
+			"proposed":  result.Proposed,
+			"dismissed": result.Dismissed,
+			"skipped":   parsed.Skipped,
+		})
+
+	default:
+		httpError(w, r, "err.method", 405)
+	}
+}
+
+type discoveryStoreResult struct {
+	Matched   []discoveryMatch
+	Proposed  []deviceDiscovery
+	Dismissed int
+}
+
+// storeDiscovery сверяет найденное со схемой и пополняет очередь
+func storeDiscovery(tx *sql.Tx, parsed *discoveryResult, lang string) (*discoveryStoreResult, error) {
+	config, err := loadWorkplaces(tx)
+	if err != nil {
+		return nil, err
+	}
+	byMAC := map[string]workplaceDevice{}
+	for _, d := range config.Devices {
+		if _, ok := byMAC[d.MAC]; !ok && d.MAC != "" {
+			byMAC[d.MAC] = d
+		}
+	}
+
+	res := &discoveryStoreResult{Matched: []discoveryMatch{}, Proposed: []deviceDiscovery{}}
+	now := time.Now().UTC().Format(dumpTimeFormat)
+	for _, h := range parsed.Hosts {
+		if d, ok := byMAC[h.MAC]; ok {
+			res.Matched = append(res.Matched, discoveryMatch{h, d.ID, d.RoomID, config.roomName(d.RoomID, lang), d.Name})
+			continue
+		}
+		// Одобренный раньше, но уже удалённый со схемы — снова в очередь
+		_, err := tx.Exec(`INSERT INTO device_discoveries (mac, ip, hostname, vendor, source, seen_at, status)
+			VALUES (?, ?, ?, ?, ?, ?, 'pending')
+			ON CONFLICT(mac) DO UPDATE SET
+				ip = CASE WHEN excluded.ip <> '' THEN excluded.ip ELSE ip END,
+				hostname = CASE WHEN excluded.hostname <> '' THEN excluded.hostname ELSE hostname END,
+				vendor = excluded.vendor, source = excluded.source, seen_at = excluded.seen_at,
+				status = CASE WHEN status = 'approved' THEN 'pending' ELSE status END,
+				device_id = CASE WHEN status = 'approved' THEN NULL ELSE device_id END`,
+			h.MAC, h.IP, h.Hostname, h.Vendor, parsed.Format, now)
+		if err != nil {
+			return nil, err
+		}
+		d, err := scanDiscovery(tx.QueryRow("SELECT "+discoveryColumns+" FROM device_discoveries WHERE mac = ?", h.MAC))
+		if err != nil {
+			return nil, err
+		}
+		if d.Status == discoveryDismissed {
+			res.Dismissed++
+			continue
+		}
+		res.Proposed = append(res.Proposed, *d)
+	}
+	return res, nil
+}
+
+// relinkDiscoveries — после замены рабочих мест из дампа device_id одобренных
+// записей указывают на удалённые или чужие устройства: связываем их заново по
+// MAC, а те, чьего MAC на схеме нет, возвращаем в очередь
+func relinkDiscoveries(tx *sql.Tx) error {
+	_, err := tx.Exec(`UPDATE device_discoveries
+		SET device_id = (SELECT MIN(d.id) FROM devices d WHERE d.mac = device_discoveries.mac)
+		WHERE status = 'approved'`)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec("UPDATE device_discoveries SET status = 'pending' WHERE status = 'approved' AND device_id IS NULL")
+	return err
+}
+
+// handleDiscoveryAction — /api/workplaces/discovery/{approve|dismiss}
+func handleDiscoveryAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	action := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/workplaces/discovery/"), "/")
+	if action != "approve" && action != "dismiss" {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+	var in struct {
+		IDs    []int64 `json:"ids"`
+		RoomID *int64  `json:"roomId"`
+		Type   string  `json:"type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		httpError(w, r, "err.invalid_json", 400)
+		return
+	}
+	if len(in.IDs) == 0 {
+		httpError(w, r, "discovery.ids_required", 400)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	pending := make([]*deviceDiscovery, 0, len(in.IDs))
+	for _, id := range in.IDs {
+		d, err := scanDiscovery(tx.QueryRow("SELECT "+discoveryColumns+" FROM device_discoveries WHERE id = ?", id))
+		if err != nil && err != sql.ErrNoRows {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if d == nil {
+			httpError(w, r, "discovery.not_found", 404, id)
+			return
+		}
+		if d.Status != discoveryPending {
+			httpError(w, r, "discovery.not_pending", http.StatusConflict, id)
+			return
+		}
+		pending = append(pending, d)
+	}
+
+	if action == "dismiss" {
+		for _, d := range pending {
+			if _, err := tx.Exec("UPDATE device_discoveries SET status = 'dismissed' WHERE id = ?", d.ID); err != nil {
+				httpError(w, r, "err.save", 500)
+				return
+			}
+		}
+		err = recordAudit(tx, r, "discovery.dismiss", "discovery", "dismiss", pending, nil)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		writeEntity(w, 200, map[string]int{"dismissed": len(pending)})
+		return
+	}
+
+	devices, err := approveDiscoveries(tx, r, pending, in.RoomID, in.Type)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		if isInputError(err) {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+		httpError(w, r, "err.save", 500)
+		return
+	}
+	writeEntity(w, 201, devices)
+}
+
+// approveDiscoveries создаёт устройства в кабинете рядами по четыре ниже
+// уже стоящих там; имя — имя хоста из DHCP
+func approveDiscoveries(tx *sql.Tx, r *http.Request, pending []*deviceDiscovery, roomID *int64, typ string) ([]workplaceDevice, error) {
+	if err := checkRoomExists(tx, roomID); err != nil {
+		return nil, err
+	}
+	var maxY float64
+	if err := tx.QueryRow("SELECT COALESCE(MAX(y), 0) FROM devices WHERE room_id IS ?", roomID).Scan(&maxY); err != nil {
+		return nil, err
+	}
+	startY := 200.0
+	if maxY > 0 {
+		startY = maxY + 400
+	}
+
+	devices := []workplaceDevice{}
+	for i, p := range pending {
+		d := workplaceDevice{
+			RoomID:     roomID,
+			Name:       p.Hostname,
+			MAC:        p.MAC,
+			Type:       typ,
+			X:          float64(200 + (i%4)*400),
+			Y:          startY + float64(i/4*400),
+			LicenseIDs: []int64{},
+		}
+		if err := normalizeDevice(&d); err != nil {
+			return nil, err
+		}
+		if err := checkMACFree(tx, d.MAC, 0); err != nil {
+			return nil, err
+		}
+		if err := insertDevice(tx, &d); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec("UPDATE device_discoveries SET status = 'approved', device_id = ? WHERE id = ?", d.ID, p.ID); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(tx, r, "device.create", "device", strconv.FormatInt(d.ID, 10), nil, d); err != nil {
+			return nil, err
+		}
+		devices = append(devices, d)
+	}
+	return devices, bumpWorkplacesRevision(tx)
+}
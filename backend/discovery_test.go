@@ -0,0 +1,141 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testDhcpdLeases = `# The format of this file is documented in the dhcpd.leases(5) manual page.
+lease 192.168.1.10 {
+  starts 4 2026/01/01 10:00:00;
+  ends 4 2099/01/01 10:00:00;
+  binding state active;
+  next binding state free;
+  hardware ethernet 00:1a:2b:3c:4d:5e;
+  client-hostname "pc-ivanov";
+}
+lease 192.168.1.11 {
+  ends 3 2020/01/01 10:00:00;
+  binding state active;
+  hardware ethernet 00:1a:2b:3c:4d:5f;
+}
+lease 192.168.1.12 {
+  binding state free;
+  hardware ethernet 00:1a:2b:3c:4d:60;
+}
+lease 192.168.1.13 {
+  binding state abandoned;
+  hardware ethernet 00:1a:2b:3c:4d:61;
+}
+lease 192.168.1.14 {
+  ends never;
+  binding state active;
+  hardware ethernet 00:1a:2b:3c:4d:62;
+}
+lease 192.168.1.14 {
+  binding state free;
+  hardware ethernet 00:1a:2b:3c:4d:62;
+}
+lease 192.168.1.15 {
+  ends epoch 4102444800; # 2100-01-01
+  binding state active;
+  hardware ethernet 00:1a:2b:3c:4d:63;
+  client-hostname "srv-01";
+}
+`
+
+const testDnsmasqLeases = `1792411200 00:1a:2b:3c:4d:5e 192.168.1.10 pc-ivanov 01:00:1a:2b:3c:4d:5e
+1792411200 00:1a:2b:3c:4d:5f 192.168.1.11 * *
+duid 00:01:00:01:2a:6b:8c:1d:00:1a:2b:3c:4d:5e
+1792411200 305419896 fd00::10 host6 00:01:00:01:2a:6b:8c:1d:00:1a:2b:3c:4d:5e
+`
+
+const testIPNeigh = `192.168.1.1 dev eth0 lladdr 00:1a:2b:3c:4d:5e REACHABLE
+192.168.1.2 dev eth0  FAILED
+fe80::1 dev eth0 lladdr 00:1a:2b:3c:4d:5f router STALE
+192.168.1.3 dev eth0 lladdr 00:1a:2b:3c:4d:60 STALE
+`
+
+const testARPLinux = `pc-ivanov (192.168.1.10) at 00:1a:2b:3c:4d:5e [ether] on eth0
+? (192.168.1.11) at 00:1a:2b:3c:4d:5f [ether] on eth0
+? (192.168.1.12) at <incomplete> on eth0
+`
+
+const testARPWindows = "\r\nInterface: 192.168.1.5 --- 0xb\r\n" +
+	"  Internet Address      Physical Address      Type\r\n" +
+	"  192.168.1.1           00-1a-2b-3c-4d-5e     dynamic\r\n" +
+	"  192.168.1.255         ff-ff-ff-ff-ff-ff     static\r\n" +
+	"  224.0.0.22            01-00-5e-00-00-16     static\r\n"
+
+const testProcNetARP = `IP address       HW type     Flags       HW address            Mask     Device
+192.168.1.1      0x1         0x2         00:1a:2b:3c:4d:5e     *        eth0
+192.168.1.7      0x1         0x0         00:00:00:00:00:00     *        eth0
+`
+
+// hostsString — «MAC IP имя» по хостам, через запятую
+func hostsString(hosts []discoveredHost) string {
+	parts := make([]string, len(hosts))
+	for i, h := range hosts {
+		parts[i] = strings.TrimSpace(h.MAC + " " + h.IP + " " + h.Hostname)
+	}
+	return strings.Join(parts, ", ")
+}
+
+func TestParseDiscovery(t *testing.T) {
+	cases := []struct {
+		name, data, format string
+		hosts              string
+	}{
+		{"dhcpd", testDhcpdLeases, discoveryDHCPD,
+			"00:1A:2B:3C:4D:5E 192.168.1.10 pc-ivanov, 00:1A:2B:3C:4D:63 192.168.1.15 srv-01"},
+		{"dnsmasq", testDnsmasqLeases, discoveryDnsmasq,
+			"00:1A:2B:3C:4D:5E 192.168.1.10 pc-ivanov, 00:1A:2B:3C:4D:5F 192.168.1.11"},
+		{"ip neigh", testIPNeigh, discoveryIPNeigh,
+			"00:1A:2B:3C:4D:5E 192.168.1.1, 00:1A:2B:3C:4D:60 192.168.1.3"},
+		{"arp -a Linux", testARPLinux, discoveryARP,
+			"00:1A:2B:3C:4D:5E 192.168.1.10 pc-ivanov, 00:1A:2B:3C:4D:5F 192.168.1.11"},
+		{"arp -a Windows", testARPWindows, discoveryARP,
+			"00:1A:2B:3C:4D:5E 192.168.1.1"},
+		{"/proc/net/arp", testProcNetARP, discoveryARP,
+			"00:1A:2B:3C:4D:5E 192.168.1.1"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			lines := strings.Split(strings.ReplaceAll(c.data, "\r\n", "\n"), "\n")
+			if got := detectDiscoveryFormat(lines); got != c.format {
+				t.Errorf("формат %q, ожидался %q", got, c.format)
+			}
+			res, err := parseDiscovery(c.data, "", "ru")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hostsString(res.Hosts); got != c.hosts {
+				t.Errorf("хосты:\n  %s\nожидались:\n  %s", got, c.hosts)
+			}
+			if len(res.Skipped) > 0 {
+				t.Errorf("пропущены строки: %+v", res.Skipped)
+			}
+		})
+	}
+}
+
+func TestParseDiscoveryErrors(t *testing.T) {
+	if _, err := parseDiscovery("hello\nworld\n", "", "ru"); err == nil {
+		t.Error("неизвестный формат разобран без ошибки")
+	}
+
+	res, err := parseDiscovery("1792411200 00:1a:2b:3c:4d:5e 192.168.1.10 pc\nmalformed line\n", discoveryDnsmasq, "ru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Hosts) != 1 || len(res.Skipped) != 1 || res.Skipped[0].Line != 2 {
+		t.Errorf("hosts=%v skipped=%+v", res.Hosts, res.Skipped)
+	}
+
+	// Последняя запись по MAC дополняет первую
+	res, _ = parseDiscovery("192.168.1.1 dev eth0 lladdr 00:1a:2b:3c:4d:5e STALE\n"+
+		"192.168.1.9 dev eth0 lladdr 00-1A-2B-3C-4D-5E REACHABLE\n", discoveryIPNeigh, "ru")
+	if got := hostsString(res.Hosts); got != "00:1A:2B:3C:4D:5E 192.168.1.9" {
+		t.Errorf("слияние по MAC: %s", got)
+	}
+}
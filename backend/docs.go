@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// === ЮРИДИЧЕСКИЕ ДОКУМЕНТЫ ===
+// /api/docs/{eula,privacy,offer,payment,invoice}; eula-table — см. eula_table.go
+// По умолчанию — HTML-страница, с ?format=pdf — PDF с нумерацией страниц
+// и реквизитами в нижнем колонтитуле (см. docs_pdf.go). Тексты — шаблоны
+// (см. templates.go) в редакциях (см. documents.go).
+
+// docRequisites — реквизиты компании для шаблонов и колонтитула
+type docRequisites struct {
+	CompanyName, LegalName, INN, OGRN, Address, Email, Website, Year, Date string
+}
+
+// loadSettings — все настройки одним запросом
+func loadSettings() map[string]string {
+	settings := map[string]string{}
+	rows, err := db.Query("SELECT key, COALESCE(value,'') FROM settings")
+	if err != nil {
+		return settings
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var k, v string
+		if rows.Scan(&k, &v) == nil {
+			settings[k] = v
+		}
+	}
+	return settings
+}
+
+// requisitesFrom — реквизиты из настроек; отсутствующие берутся из значений по умолчанию.
+// Дата — в формате языка lang.
+func requisitesFrom(settings map[string]string, lang string) docRequisites {
+	get := func(key string) string {
+		if v, ok := settings[key]; ok {
+			return v
+		}
+		return defaultSettings[key]
+	}
+	return docRequisites{
+		CompanyName: get("company_name"),
+		LegalName:   get("legal_name"),
+		INN:         get("inn"),
+		OGRN:        get("ogrn"),
+		Address:     get("legal_address"),
+		Email:       get("support_email"),
+		Website:     get("website"),
+		Year:        strconv.Itoa(time.Now().Year()),
+		Date:        time.Now().Format(msg(lang, "fmt.date")),
+	}
+}
+
+func loadRequisites(lang string) docRequisites {
+	return requisitesFrom(loadSettings(), lang)
+}
+
+// wantPDF — запрошен ли PDF вместо HTML
+func wantPDF(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch r.URL.Query().Get("format") {
+	case "", "html":
+		return false, true
+	case "pdf":
+		return true, true
+	}
+	httpError(w, r, "param.choice", 400, "format", "html, pdf")
+	return false, false
+}
+
+// renderDoc — действующая редакция документа key на языке запроса или
+// ?version=N (только опубликованные), выполненная как шаблон (см. templates.go, documents.go)
+func renderDoc(w http.ResponseWriter, r *http.Request, key string) {
+	pdf, ok := wantPDF(w, r)
+	if !ok {
+		return
+	}
+
+	lang := requestLang(r)
+	var doc *docVersion
+	var err error
+	if s := r.URL.Query().Get("version"); s != "" {
+		n, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			httpError(w, r, "param.version", 400, "version")
+			return
+		}
+		doc, err = docVersionByNumber(db, key, n)
+		if doc != nil && doc.Status != docPublished {
+			doc = nil
+		}
+	} else {
+		doc, err = currentDocVersion(db, key, lang)
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if doc == nil {
+		httpError(w, r, "doc.not_published", 404)
+		return
+	}
+
+	ctx := loadDocContext(doc.Locale)
+	ctx.Document = doc
+	writeDoc(w, r, key, ctx, pdf, path.Base(r.URL.Path))
+}
+
+// writeDoc выполняет шаблон редакции ctx.Document и отдаёт HTML-страницу или PDF с именем name.
+// Заголовок и служебные надписи — на языке редакции.
+func writeDoc(w http.ResponseWriter, r *http.Request, key string, ctx *docContext, pdf bool, name string) {
+	src := ctx.Document.Body
+	title := docTitle(ctx.Lang, key)
+	w.Header().Set("Content-Language", ctx.Lang)
+
+	if pdf {
+		content, err := renderDocText(key, src, ctx)
+		if err != nil {
+			log.Printf("Ошибка шаблона %s: %v", key, err)
+			httpError(w, r, "doc.render_error", 500, err)
+			return
+		}
+		doc := newDocPDF(ctx.Lang, title, ctx.Company)
+		doc.text(content)
+		doc.write(w, name)
+		return
+	}
+
+	body, err := renderDocHTML(key, src, ctx)
+	if err != nil {
+		log.Printf("Ошибка шаблона %s: %v", key, err)
+		httpError(w, r, "doc.render_error", 500, err)
+		return
+	}
+	var page bytes.Buffer
+	err = docPageTemplate.Execute(&page, map[string]any{
+		"Lang": ctx.Lang, "Title": title, "Body": body, "Company": ctx.Company, "TIN": msg(ctx.Lang, "doc.tin"),
+	})
+	if err != nil {
+		log.Printf("Ошибка страницы %s: %v", key, err)
+		httpError(w, r, "doc.build_error", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page.Bytes())
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// === PDF-ВЕРСИИ ДОКУМЕНТОВ ===
+// Чистый Go: fpdf + шрифты Go (в них есть кириллица), без внешних сервисов.
+// A4, поля 20 мм; в колонтитуле — реквизиты компании и «Страница N из M».
+
+type docPDF struct {
+	*fpdf.Fpdf
+	lang string
+}
+
+// newDocPDF — документ с заголовком title; подписи колонтитула на языке lang
+func newDocPDF(lang, title string, req docRequisites) *docPDF {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes("Go", "", goregular.TTF)
+	pdf.AddUTF8FontFromBytes("Go", "B", gobold.TTF)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 28)
+	pdf.AliasNbPages("{nb}")
+	pdf.SetTitle(title, true)
+	pdf.SetAuthor(req.LegalName, true)
+	pdf.SetCreator(req.CompanyName, true)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-22)
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Line(20, pdf.GetY(), 190, pdf.GetY())
+		pdf.SetFont("Go", "", 8)
+		pdf.SetTextColor(100, 100, 100)
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s • %s %s • %s %s", req.LegalName, msg(lang, "doc.tin"), req.INN, msg(lang, "doc.ogrn"), req.OGRN), "", 1, "C", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s • %s • %s", req.Address, req.Email, req.Website), "", 1, "C", false, 0, "")
+		pdf.CellFormat(0, 5, msg(lang, "doc.page", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	pdf.AddPage()
+	pdf.SetFont("Go", "B", 16)
+	pdf.SetTextColor(79, 70, 229)
+	pdf.MultiCell(0, 8, title, "", "C", false)
+	pdf.Ln(6)
+	return &docPDF{pdf, lang}
+}
+
+var (
+	htmlBreaks = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>|</h[1-6]>|</tr>`)
+	htmlTags   = regexp.MustCompile(`<[^>]*>`)
+)
+
+// plainText — текст документа без HTML-разметки (если её вставили в настройках)
+func plainText(s string) string {
+	s = htmlBreaks.ReplaceAllString(s, "\n")
+	s = htmlTags.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+// text — основной текст с переносами; абзацы разделяются пустой строкой
+func (d *docPDF) text(s string) {
+	d.SetFont("Go", "", 11)
+	d.SetTextColor(31, 41, 55)
+	d.MultiCell(0, 6, strings.TrimSpace(plainText(s)), "", "J", false)
+}
+
+// note — мелкая приписка под основным содержимым
+func (d *docPDF) note(s string) {
+	d.Ln(8)
+	d.SetFont("Go", "", 9)
+	d.SetTextColor(100, 116, 139)
+	d.MultiCell(0, 5, s, "", "C", false)
+}
+
+// table — таблица с повтором шапки на каждой новой странице
+func (d *docPDF) table(header []string, widths []float64, rows [][]string) {
+	drawHeader := func() {
+		d.SetFont("Go", "B", 10)
+		d.SetFillColor(99, 102, 241)
+		d.SetTextColor(255, 255, 255)
+		for i, h := range header {
+			d.CellFormat(widths[i], 8, h, "1", 0, "C", true, 0, "")
+		}
+		d.Ln(-1)
+		d.SetFont("Go", "", 10)
+		d.SetTextColor(31, 41, 55)
+	}
+
+	drawHeader()
+	_, pageHeight := d.GetPageSize()
+	_, _, _, bottom := d.GetMargins()
+	for _, row := range rows {
+		if d.GetY()+7 > pageHeight-bottom {
+			d.AddPage()
+			drawHeader()
+		}
+		for i, cell := range row {
+			d.CellFormat(widths[i], 7, cell, "1", 0, "L", false, 0, "")
+		}
+		d.Ln(-1)
+	}
+}
+
+// write отдаёт PDF; документ собирается в буфер, чтобы ошибка не оборвала ответ
+func (d *docPDF) write(w http.ResponseWriter, name string) {
+	var buf bytes.Buffer
+	if err := d.Output(&buf); err != nil {
+		log.Printf("Ошибка формирования PDF %s: %v", name, err)
+		http.Error(w, msg(d.lang, "doc.pdf_error"), 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "inline;filename="+name+".pdf")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,703 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ВЕРСИИ ЮРИДИЧЕСКИХ ДОКУМЕНТОВ ===
+// Тексты документов (ключи docTemplates) хранятся в document_versions, а не в
+// settings: каждое изменение — новая редакция. Черновик можно править и
+// удалять, опубликованная редакция неизменна. Действующая редакция —
+// опубликованная с наибольшей датой вступления в силу не позже сегодняшней,
+// так что публикацию можно запланировать заранее.
+//
+// У каждой редакции есть язык (locale: ru, en). Номера редакций сквозные для
+// документа, поэтому номер однозначно указывает на текст на любом языке.
+// Действующая редакция выбирается по языку запроса (?lang=, Accept-Language),
+// если на этом языке опубликованных нет — русская.
+//
+// GET    /api/documents                              — документы и их действующие редакции
+// GET    /api/documents/{key}/versions               — история редакций, ?lang= — только на этом языке
+// POST   /api/documents/{key}/versions               — новая редакция {locale, body, comment, effective_from, publish}
+// GET    /api/documents/{key}/versions/{n}           — редакция целиком
+// PUT    /api/documents/{key}/versions/{n}           — правка черновика
+// DELETE /api/documents/{key}/versions/{n}           — удаление черновика
+// POST   /api/documents/{key}/versions/{n}/publish   — публикация {effective_from}
+// GET    /api/documents/{key}/versions/{n}/preview   — просмотр (в т.ч. черновика), ?format=pdf
+// GET    /api/documents/{key}/diff?from=1&to=2       — построчное сравнение, ?format=text
+
+const (
+	docDraft     = "draft"
+	docPublished = "published"
+)
+
+type docVersion struct {
+	ID            int64  `json:"id,omitempty"`
+	Key           string `json:"key"`
+	Locale        string `json:"locale"`
+	Version       int    `json:"version"`
+	Status        string `json:"status"`
+	Body          string `json:"body,omitempty"`
+	EffectiveFrom string `json:"effective_from,omitempty"` // ГГГГ-ММ-ДД
+	Author        string `json:"author"`
+	Comment       string `json:"comment,omitempty"`
+	CreatedAt     string `json:"created_at"`
+	PublishedAt   string `json:"published_at,omitempty"`
+}
+
+const docVersionColumns = `id, doc_key, locale, version, status, body, COALESCE(effective_from, ''),
+	author, COALESCE(comment, ''), created_at, COALESCE(published_at, '')`
+
+func scanDocVersion(row interface{ Scan(...any) error }) (*docVersion, error) {
+	var v docVersion
+	err := row.Scan(&v.ID, &v.Key, &v.Locale, &v.Version, &v.Status, &v.Body, &v.EffectiveFrom,
+		&v.Author, &v.Comment, &v.CreatedAt, &v.PublishedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func today() string {
+	return time.Now().Format("2006-01-02")
+}
+
+// currentDocVersion — действующая редакция на языке lang (иначе на русском)
+// или nil, если опубликованных нет
+func currentDocVersion(q queryRower, key, lang string) (*docVersion, error) {
+	return scanDocVersion(q.QueryRow(`SELECT `+docVersionColumns+` FROM document_versions
+		WHERE doc_key = ? AND status = ? AND effective_from <= ? AND locale IN (?, ?)
+		ORDER BY locale = ? DESC, effective_from DESC, version DESC LIMIT 1`,
+		key, docPublished, today(), lang, defaultLang, lang))
+}
+
+// currentDocVersions — номера действующих редакций по языкам
+func currentDocVersions(q queryRower, key string) (map[string]int, error) {
+	versions := map[string]int{}
+	for _, lang := range supportedLangs {
+		v, err := currentDocVersion(q, key, lang)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && v.Locale == lang {
+			versions[lang] = v.Version
+		}
+	}
+	return versions, nil
+}
+
+func docVersionByNumber(q queryRower, key string, n int) (*docVersion, error) {
+	return scanDocVersion(q.QueryRow(`SELECT `+docVersionColumns+` FROM document_versions
+		WHERE doc_key = ? AND version = ?`, key, n))
+}
+
+// insertDocVersion добавляет редакцию со следующим номером (сквозным для всех языков)
+func insertDocVersion(tx *sql.Tx, v *docVersion) error {
+	if v.Locale == "" {
+		v.Locale = defaultLang
+	}
+	if err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) + 1 FROM document_versions WHERE doc_key = ?", v.Key).Scan(&v.Version); err != nil {
+		return err
+	}
+	v.CreatedAt = time.Now().UTC().Format(dumpTimeFormat)
+	if v.Status == docPublished {
+		v.PublishedAt = v.CreatedAt
+		if v.EffectiveFrom == "" {
+			v.EffectiveFrom = today()
+		}
+	}
+	res, err := tx.Exec(`INSERT INTO document_versions
+		(doc_key, locale, version, status, body, effective_from, author, comment, created_at, published_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, NULLIF(?, ''))`,
+		v.Key, v.Locale, v.Version, v.Status, v.Body, v.EffectiveFrom, v.Author, v.Comment, v.CreatedAt, v.PublishedAt)
+	if err != nil {
+		return err
+	}
+	v.ID, _ = res.LastInsertId()
+	return nil
+}
+
+// publishDocText публикует текст на языке lang новой редакцией, если он отличается
+// от действующей. Используется сохранением настроек, сбросом и восстановлением из старых дампов.
+func publishDocText(tx *sql.Tx, r *http.Request, key, lang, body, comment string) error {
+	current, err := currentDocVersion(tx, key, lang)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.Locale == lang && current.Body == body {
+		return nil
+	}
+	v := &docVersion{Key: key, Locale: lang, Status: docPublished, Body: body, Author: auditActor(r), Comment: comment}
+	if err := insertDocVersion(tx, v); err != nil {
+		return err
+	}
+	return recordAudit(tx, r, "document.publish", "document", fmt.Sprintf("%s/%d", key, v.Version), current, v)
+}
+
+// migrateDocuments переносит тексты документов из settings в document_versions:
+// документ без русских редакций получает редакцию из настроек (или значения по
+// умолчанию), без английских — из английских текстов по умолчанию; строки
+// settings с текстами удаляются
+func migrateDocuments(tx *sql.Tx) error {
+	for key := range docTemplates {
+		for _, lang := range supportedLangs {
+			var n int
+			if err := tx.QueryRow("SELECT COUNT(*) FROM document_versions WHERE doc_key = ? AND locale = ?", key, lang).Scan(&n); err != nil {
+				return err
+			}
+			if n > 0 {
+				continue
+			}
+			var body sql.NullString
+			comment := "Текст по умолчанию"
+			if lang == defaultLang {
+				tx.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&body)
+				comment = "Перенесено из настроек"
+			}
+			if !body.Valid {
+				body.String = defaultDocument(lang, key)
+			}
+			v := &docVersion{Key: key, Locale: lang, Status: docPublished, Body: body.String, Author: "system", Comment: comment}
+			if err := insertDocVersion(tx, v); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("DELETE FROM settings WHERE key = ?", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func initDocuments() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := migrateDocuments(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// === API ===
+func handleDocuments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	type docSummary struct {
+		Key      string         `json:"key"`
+		Title    string         `json:"title"`
+		Current  *docVersion    `json:"current"`
+		Locales  map[string]int `json:"locales"` // язык → номер действующей редакции
+		Latest   int            `json:"latest_version"`
+		Drafts   int            `json:"drafts"`
+		Versions int            `json:"versions"`
+	}
+	lang := requestLang(r)
+	list := []docSummary{}
+	for key := range docTemplates {
+		s := docSummary{Key: key, Title: docTitle(lang, key)}
+		current, err := currentDocVersion(db, key, lang)
+		if err == nil {
+			s.Locales, err = currentDocVersions(db, key)
+		}
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if current != nil {
+			current.Body = ""
+			s.Current = current
+		}
+		db.QueryRow(`SELECT COALESCE(MAX(version), 0), COUNT(*), COALESCE(SUM(status = ?), 0)
+			FROM document_versions WHERE doc_key = ?`, docDraft, key).Scan(&s.Latest, &s.Versions, &s.Drafts)
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	json.NewEncoder(w).Encode(list)
+}
+
+// handleDocument разбирает /api/documents/{key}/...
+func handleDocument(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/documents/"), "/"), "/")
+	key := parts[0]
+	if _, ok := docTemplates[key]; !ok {
+		httpError(w, r, "doc.unknown", 404)
+		return
+	}
+
+	switch {
+	case len(parts) == 2 && parts[1] == "versions":
+		switch r.Method {
+		case "GET":
+			listDocVersions(w, r, key)
+		case "POST":
+			createDocVersion(w, r, key)
+		default:
+			httpError(w, r, "err.method", 405)
+		}
+		return
+	case len(parts) == 2 && parts[1] == "diff":
+		handleDocDiff(w, r, key)
+		return
+	case len(parts) < 3 || parts[1] != "versions":
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+
+	n, err := strconv.Atoi(parts[2])
+	if err != nil {
+		httpError(w, r, "doc.bad_version", 400)
+		return
+	}
+	action := ""
+	if len(parts) > 3 {
+		action = parts[3]
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		v, err := docVersionByNumber(db, key, n)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if v == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	case action == "" && r.Method == "PUT":
+		updateDocDraft(w, r, key, n)
+	case action == "" && r.Method == "DELETE":
+		deleteDocDraft(w, r, key, n)
+	case action == "publish" && r.Method == "POST":
+		publishDocVersion(w, r, key, n)
+	case action == "preview" && r.Method == "GET":
+		pdf, ok := wantPDF(w, r)
+		if !ok {
+			return
+		}
+		v, err := docVersionByNumber(db, key, n)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if v == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		ctx := loadDocContext(v.Locale)
+		ctx.Document = v
+		writeDoc(w, r, key, ctx, pdf, fmt.Sprintf("%s-v%d", key, n))
+	default:
+		httpError(w, r, "err.method", 405)
+	}
+}
+
+func listDocVersions(w http.ResponseWriter, r *http.Request, key string) {
+	where, args := "doc_key = ?", []any{key}
+	if lang := normalizeLang(r.URL.Query().Get("lang")); lang != "" {
+		where, args = where+" AND locale = ?", append(args, lang)
+	}
+	rows, err := db.Query(`SELECT `+docVersionColumns+` FROM document_versions
+		WHERE `+where+` ORDER BY version DESC`, args...)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer rows.Close()
+	current, err := currentDocVersions(db, key)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+
+	type versionItem struct {
+		*docVersion
+		Current bool `json:"current"`
+	}
+	list := []versionItem{}
+	for rows.Next() {
+		v, err := scanDocVersion(rows)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		v.Body = ""
+		list = append(list, versionItem{v, current[v.Locale] == v.Version})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+type docVersionInput struct {
+	Locale        string  `json:"locale"` // только при создании; по умолчанию — язык запроса
+	Body          *string `json:"body"`
+	Comment       *string `json:"comment"`
+	EffectiveFrom *string `json:"effective_from"`
+	Publish       bool    `json:"publish"`
+}
+
+// checkDocInput проверяет шаблон на языке lang и дату; ответ об ошибке уже отправлен, если false
+func checkDocInput(w http.ResponseWriter, r *http.Request, key, lang string, in docVersionInput) bool {
+	if in.Body != nil {
+		if err := validateDocTemplates(map[string]string{key: *in.Body}, lang); err != nil {
+			httpError(w, r, "doc.template_error", 400, errorText(r, err))
+			return false
+		}
+	}
+	if in.EffectiveFrom != nil && *in.EffectiveFrom != "" {
+		if _, err := time.Parse("2006-01-02", *in.EffectiveFrom); err != nil {
+			httpError(w, r, "param.date", 400, "effective_from")
+			return false
+		}
+	}
+	return true
+}
+
+func createDocVersion(w http.ResponseWriter, r *http.Request, key string) {
+	var in docVersionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		httpError(w, r, "err.invalid_json", 400)
+		return
+	}
+	if in.Body == nil {
+		httpError(w, r, "doc.body_required", 400)
+		return
+	}
+	lang := requestLang(r)
+	if in.Locale != "" {
+		if lang = normalizeLang(in.Locale); lang != in.Locale {
+			httpError(w, r, "param.choice", 400, "locale", strings.Join(supportedLangs, ", "))
+			return
+		}
+	}
+	if !checkDocInput(w, r, key, lang, in) {
+		return
+	}
+
+	v := &docVersion{Key: key, Locale: lang, Status: docDraft, Body: *in.Body, Author: auditActor(r)}
+	if in.Comment != nil {
+		v.Comment = *in.Comment
+	}
+	if in.EffectiveFrom != nil {
+		v.EffectiveFrom = *in.EffectiveFrom
+	}
+	if in.Publish {
+		v.Status = docPublished
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	err = insertDocVersion(tx, v)
+	if err == nil {
+		action := "document.draft"
+		if in.Publish {
+			action = "document.publish"
+		}
+		err = recordAudit(tx, r, action, "document", fmt.Sprintf("%s/%d", key, v.Version), nil, v)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(201)
+	json.NewEncoder(w).Encode(v)
+}
+
+// loadDraft — черновик для изменения; ответ об ошибке уже отправлен, если nil
+func loadDraft(w http.ResponseWriter, r *http.Request, tx *sql.Tx, key string, n int) *docVersion {
+	v, err := docVersionByNumber(tx, key, n)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return nil
+	}
+	if v == nil {
+		httpError(w, r, "err.not_found", 404)
+		return nil
+	}
+	if v.Status != docDraft {
+		httpError(w, r, "doc.published_immutable", 409)
+		return nil
+	}
+	return v
+}
+
+func updateDocDraft(w http.ResponseWriter, r *http.Request, key string, n int) {
+	var in docVersionInput
+	if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+		httpError(w, r, "err.invalid_json", 400)
+		return
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	before := loadDraft(w, r, tx, key, n)
+	if before == nil {
+		return
+	}
+	if !checkDocInput(w, r, key, before.Locale, in) {
+		return
+	}
+	after := *before
+	if in.Body != nil {
+		after.Body = *in.Body
+	}
+	if in.Comment != nil {
+		after.Comment = *in.Comment
+	}
+	if in.EffectiveFrom != nil {
+		after.EffectiveFrom = *in.EffectiveFrom
+	}
+	_, err = tx.Exec(`UPDATE document_versions SET body = ?, comment = NULLIF(?, ''), effective_from = NULLIF(?, '')
+		WHERE id = ?`, after.Body, after.Comment, after.EffectiveFrom, after.ID)
+	if err == nil {
+		err = recordAudit(tx, r, "document.draft", "document", fmt.Sprintf("%s/%d", key, n), before, &after)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(after)
+}
+
+func deleteDocDraft(w http.ResponseWriter, r *http.Request, key string, n int) {
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	before := loadDraft(w, r, tx, key, n)
+	if before == nil {
+		return
+	}
+	_, err = tx.Exec("DELETE FROM document_versions WHERE id = ?", before.ID)
+	if err == nil {
+		err = recordAudit(tx, r, "document.delete", "document", fmt.Sprintf("%s/%d", key, n), before, nil)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.WriteHeader(200)
+}
+
+func publishDocVersion(w http.ResponseWriter, r *http.Request, key string, n int) {
+	var in docVersionInput
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+	}
+	in.Body = nil
+	if !checkDocInput(w, r, key, defaultLang, in) {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	before := loadDraft(w, r, tx, key, n)
+	if before == nil {
+		return
+	}
+	// Шаблон проверяется заново: данные могли измениться с момента сохранения черновика
+	if err := validateDocTemplates(map[string]string{key: before.Body}, before.Locale); err != nil {
+		httpError(w, r, "doc.template_error", 400, errorText(r, err))
+		return
+	}
+
+	after := *before
+	after.Status = docPublished
+	after.PublishedAt = time.Now().UTC().Format(dumpTimeFormat)
+	if in.EffectiveFrom != nil && *in.EffectiveFrom != "" {
+		after.EffectiveFrom = *in.EffectiveFrom
+	}
+	if after.EffectiveFrom == "" {
+		after.EffectiveFrom = today()
+	}
+	_, err = tx.Exec("UPDATE document_versions SET status = ?, published_at = ?, effective_from = ? WHERE id = ?",
+		after.Status, after.PublishedAt, after.EffectiveFrom, after.ID)
+	if err == nil {
+		err = recordAudit(tx, r, "document.publish", "document", fmt.Sprintf("%s/%d", key, n), before, &after)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Ошибка публикации %s/%d: %v", key, n, err)
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(after)
+}
+
+// === Сравнение редакций ===
+type diffLine struct {
+	Op   string `json:"op"` // " " — без изменений, "-" — удалено, "+" — добавлено
+	Text string `json:"text"`
+}
+
+// diffLines — построчный diff по наибольшей общей подпоследовательности
+func diffLines(a, b []string) []diffLine {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, diffLine{" ", a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, diffLine{"-", a[i]})
+			i++
+		default:
+			out = append(out, diffLine{"+", b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, diffLine{"-", a[i]})
+	}
+	for ; j < len(b); j++ {
+		out = append(out, diffLine{"+", b[j]})
+	}
+	return out
+}
+
+// handleDocDiff — GET /api/documents/{key}/diff?from=1&to=2;
+// без to — с действующей редакцией на языке запроса, без from — с предыдущей
+// редакцией на том же языке
+func handleDocDiff(w http.ResponseWriter, r *http.Request, key string) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	q := r.URL.Query()
+
+	var to *docVersion
+	var err error
+	if s := q.Get("to"); s != "" {
+		n, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			httpError(w, r, "param.version", 400, "to")
+			return
+		}
+		to, err = docVersionByNumber(db, key, n)
+	} else {
+		to, err = currentDocVersion(db, key, requestLang(r))
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if to == nil {
+		httpError(w, r, "doc.version_not_found", 404)
+		return
+	}
+
+	var fromN int
+	if s := q.Get("from"); s != "" {
+		if fromN, err = strconv.Atoi(s); err != nil {
+			httpError(w, r, "param.version", 400, "from")
+			return
+		}
+	} else {
+		db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM document_versions WHERE doc_key = ? AND locale = ? AND version < ?",
+			key, to.Locale, to.Version).Scan(&fromN)
+	}
+	from, err := docVersionByNumber(db, key, fromN)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if from == nil {
+		httpError(w, r, "doc.version_not_found", 404)
+		return
+	}
+
+	lines := diffLines(strings.Split(from.Body, "\n"), strings.Split(to.Body, "\n"))
+	if q.Get("format") == "text" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "--- %s v%d\n+++ %s v%d\n", key, from.Version, key, to.Version)
+		for _, l := range lines {
+			fmt.Fprintf(w, "%s %s\n", l.Op, l.Text)
+		}
+		return
+	}
+
+	added, removed := 0, 0
+	for _, l := range lines {
+		switch l.Op {
+		case "+":
+			added++
+		case "-":
+			removed++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"key":     key,
+		"from":    from.Version,
+		"to":      to.Version,
+		"added":   added,
+		"removed": removed,
+		"lines":   lines,
+	})
+}
@@ -0,0 +1,893 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ПОЛНАЯ ВЫГРУЗКА И ВОССТАНОВЛЕНИЕ (JSON / NDJSON) ===
+// В отличие от CSV/XLSX дамп переносит всё: лицензии с id и датами создания,
+// журнал активаций, настройки, редакции документов, выданные номера документов
+// по лицензиям, принятия документов клиентами и рабочие места. Журнал аудита, баны и очередь обнаруженных в
+// сети устройств не входят — они относятся к конкретному экземпляру.
+//
+// GET  /api/dump?format=json|ndjson
+// POST /api/restore?mode=merge|replace&dry_run=1 (тело — дамп или multipart file)
+//
+// NDJSON: первая строка — заголовок {"type":"header",...}, далее по строке на
+// запись {"type":"license|activation|setting|document|workplaces|license_device|license_document|acceptance",
+// "data":{...}}.
+// JSON: {"header":{...},"licenses":[...],"activations":[...],"settings":[...],"documents":[...],
+// "workplaces":{...},"license_devices":[...],"license_documents":[...],"acceptances":[...]}
+//
+// Версия схемы 2 добавила редакции документов; в дампах версии 1 тексты
+// документов лежат в settings и при восстановлении становятся новыми редакциями.
+// Версия 3 перенесла рабочие места в таблицы: {"rooms":[{id,name,position}],
+// "devices":[{id,roomId,...}]} вместо массива названий и roomId-индексов
+// (старый формат переносится через importLegacyWorkplaces).
+// Версия 4 добавила связи лицензий с устройствами {license_key, device_id,
+// linked_at}; в более старых дампах они строятся из текста activated_on.
+// Версия 5 добавила выданные номера документов по лицензиям {type, license_key,
+// seq, number, issued_at}: без них нумерация на новом экземпляре началась бы с 1,
+// и принятия документов {license_key, device, doc_key, locale, version, ...}:
+// id лицензий в replace сохраняются, и без очистки старые принятия достались бы
+// другим лицензиям.
+
+const (
+	dumpFormatName    = "license-manager-dump"
+	dumpSchemaVersion = 5
+)
+
+type dumpHeader struct {
+	Type          string         `json:"type,omitempty"` // "header" в NDJSON
+	Format        string         `json:"format"`
+	SchemaVersion int            `json:"schema_version"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Counts        map[string]int `json:"counts"`
+}
+
+type dumpActivation struct {
+	ID          int64     `json:"id"`
+	LicenseKey  string    `json:"license_key"`
+	ActivatedAt time.Time `json:"activated_at"`
+	DeviceName  string    `json:"device_name,omitempty"`
+	Browser     string    `json:"browser,omitempty"`
+}
+
+type dumpSetting struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// dumpLicenseDevice — лицензия по ключу (id в другом экземпляре может
+// отличаться), устройство по id (рабочие места восстанавливаются с id)
+type dumpLicenseDevice struct {
+	LicenseKey string `json:"license_key"`
+	DeviceID   int64  `json:"device_id"`
+	LinkedAt   string `json:"linked_at"`
+}
+
+// dumpLicenseDocument — выданный номер накладной или сертификата; лицензия по ключу
+type dumpLicenseDocument struct {
+	Type       string `json:"type"`
+	LicenseKey string `json:"license_key"`
+	Seq        int    `json:"seq"`
+	Number     string `json:"number"`
+	IssuedAt   string `json:"issued_at"`
+}
+
+// dumpAcceptance — принятие редакции документа; лицензия по ключу
+type dumpAcceptance struct {
+	LicenseKey string `json:"license_key"`
+	Device     string `json:"device,omitempty"`
+	DocKey     string `json:"doc_key"`
+	Locale     string `json:"locale"`
+	Version    int    `json:"version"`
+	AcceptedAt string `json:"accepted_at"`
+	IP         string `json:"ip,omitempty"`
+	UserAgent  string `json:"user_agent,omitempty"`
+}
+
+type dumpRecord struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+type dumpDocument struct {
+	Header           dumpHeader            `json:"header"`
+	Licenses         []License             `json:"licenses"`
+	Activations      []dumpActivation      `json:"activations"`
+	Settings         []dumpSetting         `json:"settings"`
+	Documents        []docVersion          `json:"documents"`
+	Workplaces       json.RawMessage       `json:"workplaces"`
+	LicenseDevices   []dumpLicenseDevice   `json:"license_devices"`
+	LicenseDocuments []dumpLicenseDocument `json:"license_documents"`
+	Acceptances      []dumpAcceptance      `json:"acceptances"`
+}
+
+// Время в БД — как у CURRENT_TIMESTAMP
+const dumpTimeFormat = "2006-01-02 15:04:05"
+
+func handleDump(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "ndjson" {
+		httpError(w, r, "param.choice", 400, "format", "json, ndjson")
+		return
+	}
+
+	// Одна транзакция — согласованный снимок всех таблиц
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	header := dumpHeader{
+		Format:        dumpFormatName,
+		SchemaVersion: dumpSchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Counts:        map[string]int{},
+	}
+	for name, query := range map[string]string{
+		"licenses":          "SELECT COUNT(*) FROM licenses",
+		"activations":       "SELECT COUNT(*) FROM activation_log",
+		"settings":          "SELECT COUNT(*) FROM settings",
+		"documents":         "SELECT COUNT(*) FROM document_versions",
+		"rooms":             "SELECT COUNT(*) FROM rooms",
+		"devices":           "SELECT COUNT(*) FROM devices",
+		"license_devices":   "SELECT COUNT(*) FROM license_devices",
+		"license_documents": "SELECT COUNT(*) FROM license_documents",
+		"acceptances":       "SELECT COUNT(*) FROM document_acceptances",
+	} {
+		var n int
+		tx.QueryRow(query).Scan(&n)
+		header.Counts[name] = n
+	}
+
+	stamp := header.CreatedAt.Format("20060102-150405")
+	if format == "ndjson" {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.Header().Set("Content-Disposition", "attachment;filename=licenses-"+stamp+".ndjson")
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		header.Type = "header"
+		enc.Encode(header)
+		err = writeDumpRecords(tx, func(typ string, v any) error {
+			return enc.Encode(struct {
+				Type string `json:"type"`
+				Data any    `json:"data"`
+			}{typ, v})
+		})
+		if err != nil {
+			// Заголовки уже отправлены — обрываем поток, чтобы дамп не выглядел целым
+			panic(http.ErrAbortHandler)
+		}
+		bw.Flush()
+		return
+	}
+
+	doc := dumpDocument{
+		Header:           header,
+		Licenses:         []License{},
+		Activations:      []dumpActivation{},
+		Settings:         []dumpSetting{},
+		Documents:        []docVersion{},
+		Workplaces:       json.RawMessage("null"),
+		LicenseDevices:   []dumpLicenseDevice{},
+		LicenseDocuments: []dumpLicenseDocument{},
+		Acceptances:      []dumpAcceptance{},
+	}
+	err = writeDumpRecords(tx, func(typ string, v any) error {
+		switch rec := v.(type) {
+		case License:
+			doc.Licenses = append(doc.Licenses, rec)
+		case dumpActivation:
+			doc.Activations = append(doc.Activations, rec)
+		case dumpSetting:
+			doc.Settings = append(doc.Settings, rec)
+		case docVersion:
+			doc.Documents = append(doc.Documents, rec)
+		case workplacesConfig:
+			doc.Workplaces, _ = json.Marshal(rec)
+		case dumpLicenseDevice:
+			doc.LicenseDevices = append(doc.LicenseDevices, rec)
+		case dumpLicenseDocument:
+			doc.LicenseDocuments = append(doc.LicenseDocuments, rec)
+		case dumpAcceptance:
+			doc.Acceptances = append(doc.Acceptances, rec)
+		}
+		return nil
+	})
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", "attachment;filename=licenses-"+stamp+".json")
+	json.NewEncoder(w).Encode(doc)
+}
+
+// writeDumpRecords читает таблицы по порядку и отдаёт записи в emit
+func writeDumpRecords(tx *sql.Tx, emit func(typ string, v any) error) error {
+	rows, err := tx.Query(`SELECT
+			id, key, description, expiry_date, max_uses, current_uses, created_at,
+			COALESCE(cost, 0), COALESCE(supplier, ''), COALESCE(activated_on, ''), COALESCE(product, '')
+		FROM licenses ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		l, err := scanLicense(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		l.ExpiryDate = exportDate(l.ExpiryDate)
+		if err := emit("license", l); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`SELECT id, license_key, activated_at, COALESCE(device_name, ''), COALESCE(browser, '')
+		FROM activation_log ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var a dumpActivation
+		if err := rows.Scan(&a.ID, &a.LicenseKey, &a.ActivatedAt, &a.DeviceName, &a.Browser); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := emit("activation", a); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query("SELECT key, COALESCE(value, '') FROM settings ORDER BY key")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var s dumpSetting
+		if err := rows.Scan(&s.Key, &s.Value); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := emit("setting", s); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query("SELECT " + docVersionColumns + " FROM document_versions ORDER BY doc_key, version")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		v, err := scanDocVersion(rows)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		v.ID = 0
+		if err := emit("document", *v); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	config, err := loadWorkplaces(tx)
+	if err != nil {
+		return err
+	}
+	if err := emit("workplaces", config); err != nil {
+		return err
+	}
+
+	// Связи — после лицензий и устройств, на которые ссылаются
+	rows, err = tx.Query(`SELECT l.key, ld.device_id, ld.linked_at
+		FROM license_devices ld JOIN licenses l ON l.id = ld.license_id
+		ORDER BY ld.license_id, ld.device_id`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var ld dumpLicenseDevice
+		if err := rows.Scan(&ld.LicenseKey, &ld.DeviceID, &ld.LinkedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := emit("license_device", ld); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`SELECT d.type, l.key, d.seq, d.number, d.issued_at
+		FROM license_documents d JOIN licenses l ON l.id = d.license_id
+		ORDER BY d.type, d.seq`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var d dumpLicenseDocument
+		if err := rows.Scan(&d.Type, &d.LicenseKey, &d.Seq, &d.Number, &d.IssuedAt); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := emit("license_document", d); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+
+	rows, err = tx.Query(`SELECT l.key, a.device, a.doc_key, a.locale, a.version, a.accepted_at,
+			COALESCE(a.ip, ''), COALESCE(a.user_agent, '')
+		FROM document_acceptances a JOIN licenses l ON l.id = a.license_id
+		ORDER BY a.id`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a dumpAcceptance
+		if err := rows.Scan(&a.LicenseKey, &a.Device, &a.DocKey, &a.Locale, &a.Version, &a.AcceptedAt, &a.IP, &a.UserAgent); err != nil {
+			return err
+		}
+		if err := emit("acceptance", a); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// === ВОССТАНОВЛЕНИЕ ===
+const (
+	restoreMerge   = "merge"   // лицензии обновляются по ключу, остальное добавляется
+	restoreReplace = "replace" // таблицы очищаются и заполняются из дампа с исходными id
+)
+
+type restorer struct {
+	tx      *sql.Tx
+	r       *http.Request // автор редакций из дампов версии 1
+	mode    string
+	version int // версия схемы дампа
+	counts  map[string]int
+
+	devicesReplaced bool // устройства пересозданы — очередь обнаружения надо пересвязать
+}
+
+func handleRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = restoreMerge
+	}
+	if mode != restoreMerge && mode != restoreReplace {
+		httpError(w, r, "param.choice", 400, "mode", "merge, replace")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.ImportMaxBytes)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			httpError(w, r, "err.file_required", 400)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	rs := &restorer{tx: tx, r: r, mode: mode, counts: map[string]int{}}
+	header, err := rs.run(body)
+	if err == nil {
+		// Документы, которых не было в дампе, получают текст по умолчанию
+		err = migrateDocuments(tx)
+	}
+	if err == nil {
+		// Дампы до версии 4 и лицензии, чьи связи ушли вместе с устройствами
+		err = refreshLicenseDevices(tx)
+	}
+	if err == nil && rs.devicesReplaced {
+		err = relinkDiscoveries(tx)
+	}
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, r, "err.file_too_large", http.StatusRequestEntityTooLarge, cfg.ImportMaxBytes)
+			return
+		}
+		httpError(w, r, "restore.failed", 400, errorText(r, err))
+		return
+	}
+
+	if !dryRun {
+		err = recordAudit(tx, r, "dump.restore", "dump", header.CreatedAt.Format(time.RFC3339), nil, map[string]any{
+			"mode":           mode,
+			"schema_version": header.SchemaVersion,
+			"counts":         rs.counts,
+		})
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":        true,
+		"dry_run":        dryRun,
+		"mode":           mode,
+		"schema_version": header.SchemaVersion,
+		"dump_created":   header.CreatedAt,
+		"restored":       rs.counts,
+	})
+}
+
+// run определяет формат по первому значению: {"type":"header"} — NDJSON,
+// {"header":{...}} — цельный JSON
+func (rs *restorer) run(body io.Reader) (dumpHeader, error) {
+	dec := json.NewDecoder(bufio.NewReader(body))
+
+	var first json.RawMessage
+	if err := dec.Decode(&first); err != nil {
+		return dumpHeader{}, newLocalizedError("restore.bad_dump", err)
+	}
+	var probe struct {
+		Type   string          `json:"type"`
+		Header json.RawMessage `json:"header"`
+	}
+	json.Unmarshal(first, &probe)
+
+	switch {
+	case probe.Type == "header":
+		var header dumpHeader
+		json.Unmarshal(first, &header)
+		if err := rs.begin(header); err != nil {
+			return header, err
+		}
+		for line := 2; ; line++ {
+			var rec dumpRecord
+			err := dec.Decode(&rec)
+			if err == io.EOF {
+				return header, nil
+			}
+			if err != nil {
+				return header, newLocalizedError("restore.record", line, err)
+			}
+			if err := rs.record(rec.Type, rec.Data); err != nil {
+				return header, newLocalizedError("restore.record_type", line, rec.Type, err)
+			}
+		}
+
+	case probe.Header != nil:
+		var doc dumpDocument
+		if err := json.Unmarshal(first, &doc); err != nil {
+			return doc.Header, err
+		}
+		if err := rs.begin(doc.Header); err != nil {
+			return doc.Header, err
+		}
+		for i, l := range doc.Licenses {
+			if err := rs.license(l); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("licenses[%d]", i), err)
+			}
+		}
+		for i, a := range doc.Activations {
+			if err := rs.activation(a); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("activations[%d]", i), err)
+			}
+		}
+		for i, s := range doc.Settings {
+			if err := rs.setting(s); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("settings[%d]", i), err)
+			}
+		}
+		for i, v := range doc.Documents {
+			if err := rs.document(v); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("documents[%d]", i), err)
+			}
+		}
+		if len(doc.Workplaces) > 0 && string(doc.Workplaces) != "null" {
+			if err := rs.workplaces(doc.Workplaces); err != nil {
+				return doc.Header, newLocalizedError("restore.at", "workplaces", err)
+			}
+		}
+		for i, ld := range doc.LicenseDevices {
+			if err := rs.licenseDevice(ld); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("license_devices[%d]", i), err)
+			}
+		}
+		for i, d := range doc.LicenseDocuments {
+			if err := rs.licenseDocument(d); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("license_documents[%d]", i), err)
+			}
+		}
+		for i, a := range doc.Acceptances {
+			if err := rs.acceptance(a); err != nil {
+				return doc.Header, newLocalizedError("restore.at", fmt.Sprintf("acceptances[%d]", i), err)
+			}
+		}
+		return doc.Header, nil
+	}
+	return dumpHeader{}, newLocalizedError("restore.no_header")
+}
+
+// begin проверяет заголовок и в режиме replace очищает таблицы
+func (rs *restorer) begin(h dumpHeader) error {
+	if h.Format != dumpFormatName {
+		return newLocalizedError("restore.unknown_format", h.Format)
+	}
+	if h.SchemaVersion < 1 || h.SchemaVersion > dumpSchemaVersion {
+		return newLocalizedError("restore.schema_version", h.SchemaVersion, dumpSchemaVersion)
+	}
+	rs.version = h.SchemaVersion
+	if rs.mode != restoreReplace {
+		return nil
+	}
+	rs.devicesReplaced = true
+	for _, table := range []string{"licenses", "activation_log", "settings", "document_versions", "rooms", "devices", "license_devices", "license_documents", "document_acceptances"} {
+		if _, err := rs.tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (rs *restorer) record(typ string, data json.RawMessage) error {
+	switch typ {
+	case "license":
+		var l License
+		if err := json.Unmarshal(data, &l); err != nil {
+			return err
+		}
+		return rs.license(l)
+	case "activation":
+		var a dumpActivation
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		return rs.activation(a)
+	case "setting":
+		var s dumpSetting
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		return rs.setting(s)
+	case "document":
+		var v docVersion
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+		return rs.document(v)
+	case "workplaces":
+		return rs.workplaces(data)
+	case "license_device":
+		var ld dumpLicenseDevice
+		if err := json.Unmarshal(data, &ld); err != nil {
+			return err
+		}
+		return rs.licenseDevice(ld)
+	case "license_document":
+		var d dumpLicenseDocument
+		if err := json.Unmarshal(data, &d); err != nil {
+			return err
+		}
+		return rs.licenseDocument(d)
+	case "acceptance":
+		var a dumpAcceptance
+		if err := json.Unmarshal(data, &a); err != nil {
+			return err
+		}
+		return rs.acceptance(a)
+	}
+	return newLocalizedError("restore.unknown_record", typ)
+}
+
+func (rs *restorer) license(l License) error {
+	if strings.TrimSpace(l.Key) == "" {
+		return newLocalizedError("restore.empty_key")
+	}
+	expiry, err := normalizeImportDate(l.ExpiryDate)
+	if err != nil {
+		return err
+	}
+	created := l.CreatedAt.UTC().Format(dumpTimeFormat)
+	if l.CreatedAt.IsZero() {
+		created = time.Now().UTC().Format(dumpTimeFormat)
+	}
+
+	if rs.mode == restoreReplace {
+		_, err = rs.tx.Exec(`INSERT INTO licenses
+			(id, key, description, expiry_date, max_uses, current_uses, created_at, cost, supplier, activated_on, product)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			l.ID, l.Key, l.Description, expiry, l.MaxUses, l.CurrentUses, created, l.Cost, l.Supplier, l.ActivatedOn, l.Product)
+	} else {
+		// id в другом экземпляре может быть занят — сопоставляем по ключу
+		_, err = rs.tx.Exec(`INSERT INTO licenses
+			(key, description, expiry_date, max_uses, current_uses, created_at, cost, supplier, activated_on, product)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(key) DO UPDATE SET
+				description = excluded.description, expiry_date = excluded.expiry_date,
+				max_uses = excluded.max_uses, current_uses = excluded.current_uses,
+				cost = excluded.cost, supplier = excluded.supplier,
+				activated_on = excluded.activated_on, product = excluded.product`,
+			l.Key, l.Description, expiry, l.MaxUses, l.CurrentUses, created, l.Cost, l.Supplier, l.ActivatedOn, l.Product)
+	}
+	if err == nil {
+		rs.counts["licenses"]++
+	}
+	return err
+}
+
+func (rs *restorer) activation(a dumpActivation) error {
+	at := a.ActivatedAt.UTC().Format(dumpTimeFormat)
+	var err error
+	if rs.mode == restoreReplace {
+		_, err = rs.tx.Exec(`INSERT INTO activation_log (id, license_key, activated_at, device_name, browser)
+			VALUES (?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`, a.ID, a.LicenseKey, at, a.DeviceName, a.Browser)
+	} else {
+		// Повторное восстановление того же дампа не должно дублировать журнал
+		var res sql.Result
+		res, err = rs.tx.Exec(`INSERT INTO activation_log (license_key, activated_at, device_name, browser)
+			SELECT ?, ?, NULLIF(?, ''), NULLIF(?, '')
+			WHERE NOT EXISTS (SELECT 1 FROM activation_log WHERE license_key = ? AND activated_at = ?)`,
+			a.LicenseKey, at, a.DeviceName, a.Browser, a.LicenseKey, at)
+		if err == nil {
+			if n, _ := res.RowsAffected(); n == 0 {
+				return nil
+			}
+		}
+	}
+	if err == nil {
+		rs.counts["activations"]++
+	}
+	return err
+}
+
+func (rs *restorer) setting(s dumpSetting) error {
+	if s.Key == "" {
+		return newLocalizedError("restore.empty_setting")
+	}
+	if s.Key == "eula_room_filter" {
+		// Дампы до появления параметров у /api/docs/eula-table
+		return nil
+	}
+	if _, isDoc := docTemplates[s.Key]; isDoc {
+		// Дамп версии 1: текст документа становится редакцией, если отличается
+		if err := publishDocText(rs.tx, rs.r, s.Key, defaultLang, s.Value, "Восстановлено из дампа"); err != nil {
+			return err
+		}
+		rs.counts["documents"]++
+		return nil
+	}
+	_, err := rs.tx.Exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", s.Key, s.Value)
+	if err == nil {
+		rs.counts["settings"]++
+	}
+	return err
+}
+
+// document восстанавливает редакцию с исходным номером; в режиме merge
+// уже существующие номера пропускаются
+func (rs *restorer) document(v docVersion) error {
+	if _, ok := docTemplates[v.Key]; !ok {
+		return newLocalizedError("restore.unknown_doc", v.Key)
+	}
+	if v.Version < 1 || (v.Status != docDraft && v.Status != docPublished) {
+		return newLocalizedError("restore.bad_version", v.Key, v.Version, v.Status)
+	}
+	// В дампах до появления языков редакции русские
+	if v.Locale == "" {
+		v.Locale = defaultLang
+	}
+	if normalizeLang(v.Locale) != v.Locale {
+		return newLocalizedError("restore.bad_locale", v.Key, v.Locale)
+	}
+	if v.CreatedAt == "" {
+		v.CreatedAt = time.Now().UTC().Format(dumpTimeFormat)
+	}
+	res, err := rs.tx.Exec(`INSERT OR IGNORE INTO document_versions
+		(doc_key, locale, version, status, body, effective_from, author, comment, created_at, published_at)
+		VALUES (?, ?, ?, ?, ?, NULLIF(?, ''), ?, NULLIF(?, ''), ?, NULLIF(?, ''))`,
+		v.Key, v.Locale, v.Version, v.Status, v.Body, v.EffectiveFrom, v.Author, v.Comment, v.CreatedAt, v.PublishedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		rs.counts["documents"]++
+	}
+	return nil
+}
+
+// workplaces заменяет кабинеты и устройства целиком в обоих режимах; id
+// сохраняются, потому что на них ссылаются связи с лицензиями и
+// сохранённые ссылки на таблицу EULA. Старые связи уходят вместе с устройствами
+func (rs *restorer) workplaces(data json.RawMessage) error {
+	rs.devicesReplaced = true
+	for _, table := range []string{"license_devices", "devices", "rooms"} {
+		if _, err := rs.tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+
+	if rs.version < 3 {
+		config, err := importLegacyWorkplaces(rs.tx, data)
+		if err != nil {
+			return err
+		}
+		rs.counts["rooms"] = len(config.Rooms)
+		rs.counts["devices"] = len(config.Devices)
+		return bumpWorkplacesRevision(rs.tx)
+	}
+
+	var config workplacesConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return err
+	}
+	rooms := map[int64]bool{}
+	for i := range config.Rooms {
+		room := &config.Rooms[i]
+		if room.ID < 1 || strings.TrimSpace(room.Name) == "" {
+			return newLocalizedError("restore.bad_room", i)
+		}
+		if err := insertRoom(rs.tx, room); err != nil {
+			return err
+		}
+		rooms[room.ID] = true
+	}
+	for i := range config.Devices {
+		d := &config.Devices[i]
+		if err := normalizeStoredDevice(d); err != nil {
+			return newLocalizedError("restore.at", fmt.Sprintf("devices[%d]", i), err)
+		}
+		if d.ID < 1 || (d.RoomID != nil && !rooms[*d.RoomID]) {
+			return newLocalizedError("restore.bad_device", i)
+		}
+		if err := insertDevice(rs.tx, d); err != nil {
+			return err
+		}
+	}
+	rs.counts["rooms"] = len(config.Rooms)
+	rs.counts["devices"] = len(config.Devices)
+	return bumpWorkplacesRevision(rs.tx)
+}
+
+// licenseID — id лицензии по ключу из дампа
+func (rs *restorer) licenseID(key string) (int64, error) {
+	var id int64
+	err := rs.tx.QueryRow("SELECT id FROM licenses WHERE key = ?", key).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, newLocalizedError("restore.no_license", key)
+	}
+	return id, err
+}
+
+func (rs *restorer) licenseDevice(ld dumpLicenseDevice) error {
+	licenseID, err := rs.licenseID(ld.LicenseKey)
+	if err != nil {
+		return err
+	}
+	var devices int64
+	rs.tx.QueryRow("SELECT COUNT(*) FROM devices WHERE id = ?", ld.DeviceID).Scan(&devices)
+	if devices == 0 {
+		return newLocalizedError("restore.no_device", ld.DeviceID)
+	}
+	if ld.LinkedAt == "" {
+		ld.LinkedAt = time.Now().UTC().Format(dumpTimeFormat)
+	}
+	res, err := rs.tx.Exec("INSERT OR IGNORE INTO license_devices (license_id, device_id, linked_at) VALUES (?, ?, ?)",
+		licenseID, ld.DeviceID, ld.LinkedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		rs.counts["license_devices"]++
+	}
+	return nil
+}
+
+// licenseDocument восстанавливает выданный номер с исходным seq, чтобы
+// следующий документ продолжил нумерацию. В режиме merge номер, уже занятый
+// здесь другой лицензией, пропускается: лицензия получит новый при следующем
+// формировании документа
+func (rs *restorer) licenseDocument(d dumpLicenseDocument) error {
+	if _, ok := licenseDocTypes[d.Type]; !ok {
+		return newLocalizedError("restore.unknown_doc", d.Type)
+	}
+	if d.Seq < 1 || strings.TrimSpace(d.Number) == "" {
+		return newLocalizedError("restore.bad_doc_number", d.Type, d.Seq, d.Number)
+	}
+	licenseID, err := rs.licenseID(d.LicenseKey)
+	if err != nil {
+		return err
+	}
+	if d.IssuedAt == "" {
+		d.IssuedAt = time.Now().UTC().Format(dumpTimeFormat)
+	}
+	res, err := rs.tx.Exec("INSERT OR IGNORE INTO license_documents (type, license_id, seq, number, issued_at) VALUES (?, ?, ?, ?, ?)",
+		d.Type, licenseID, d.Seq, d.Number, d.IssuedAt)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		rs.counts["license_documents"]++
+	}
+	return nil
+}
+
+// acceptance восстанавливает принятие по ключу лицензии; повтор того же
+// принятия (merge того же дампа) не дублируется
+func (rs *restorer) acceptance(a dumpAcceptance) error {
+	if _, ok := docTemplates[a.DocKey]; !ok {
+		return newLocalizedError("restore.unknown_doc", a.DocKey)
+	}
+	if a.Version < 1 {
+		return newLocalizedError("restore.bad_version", a.DocKey, a.Version, "accepted")
+	}
+	if a.Locale == "" {
+		a.Locale = defaultLang
+	}
+	if normalizeLang(a.Locale) != a.Locale {
+		return newLocalizedError("restore.bad_locale", a.DocKey, a.Locale)
+	}
+	licenseID, err := rs.licenseID(a.LicenseKey)
+	if err != nil {
+		return err
+	}
+	if a.AcceptedAt == "" {
+		a.AcceptedAt = time.Now().UTC().Format(dumpTimeFormat)
+	}
+	res, err := rs.tx.Exec(`INSERT OR IGNORE INTO document_acceptances
+		(license_id, device, doc_key, locale, version, accepted_at, ip, user_agent)
+		VALUES (?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''))`,
+		licenseID, a.Device, a.DocKey, a.Locale, a.Version, a.AcceptedAt, a.IP, a.UserAgent)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		rs.counts["acceptances"]++
+	}
+	return nil
+}
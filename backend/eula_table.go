@@ -0,0 +1,421 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// === ТАБЛИЦА РАБОЧИХ МЕСТ ДЛЯ ПРИЛОЖЕНИЯ К EULA ===
+// GET /api/docs/eula-table?format=html|pdf|json|xlsx
+//
+//	room=1&room=2 или room=1,2 — кабинеты; none — устройства вне кабинетов
+//	license=id|ключ            — устройства, к которым привязана лицензия
+//	type=desktop|laptop|tablet|server
+//	status=active|expired|unknown — unknown: без привязанных лицензий
+//
+// Без параметров — все устройства. Раньше кабинет задавала общая настройка
+// eula_room_filter, и два человека, готовящие приложения для разных
+// кабинетов, перебивали друг другу выбор. Теперь всё нужное таблице
+// приходит в запросе, а ссылку на неё можно сохранить.
+
+// eulaDeviceRow — строка таблицы с подписями на языке запроса (HTML, PDF,
+// XLSX и {{range .Workplaces.Devices}} в шаблонах)
+type eulaDeviceRow struct {
+	Room, Name, MAC, Type, Status, StatusColor string
+}
+
+// eulaTableDevice — строка таблицы в JSON
+type eulaTableDevice struct {
+	ID          int64    `json:"id"`
+	RoomID      *int64   `json:"roomId"`
+	Room        string   `json:"room"`
+	Name        string   `json:"name"`
+	MAC         string   `json:"mac"`
+	Type        string   `json:"type"`
+	TypeLabel   string   `json:"typeLabel"`
+	Status      string   `json:"status"`
+	StatusLabel string   `json:"statusLabel"`
+	LicenseIDs  []int64  `json:"licenseIds"`
+	LicenseKeys []string `json:"licenseKeys"`
+}
+
+var eulaStatuses = []string{"active", "expired", "unknown"}
+
+// eulaTableQuery — условия отбора устройств; пустое значение — все устройства
+type eulaTableQuery struct {
+	Rooms   []int64  `json:"rooms"`
+	NoRoom  bool     `json:"noRoom"` // устройства вне кабинетов
+	License *License `json:"license"`
+	Type    string   `json:"type"`
+	Status  string   `json:"status"`
+}
+
+func (f eulaTableQuery) empty() bool {
+	return len(f.Rooms) == 0 && !f.NoRoom && f.License == nil && f.Type == "" && f.Status == ""
+}
+
+// parseEulaTableQuery разбирает параметры запроса; ошибка в параметрах —
+// localizedError (см. isInputError), остальное — ошибки БД
+func parseEulaTableQuery(q queryRower, params url.Values, config workplacesConfig) (eulaTableQuery, error) {
+	f := eulaTableQuery{Rooms: []int64{}}
+	for _, v := range params["room"] {
+		for _, s := range strings.Split(v, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			if s == "none" {
+				f.NoRoom = true
+				continue
+			}
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return f, newLocalizedError("param.invalid", "room")
+			}
+			if _, ok := config.room(id); !ok {
+				return f, newLocalizedError("eula.no_room", id)
+			}
+			if !containsID(f.Rooms, id) {
+				f.Rooms = append(f.Rooms, id)
+			}
+		}
+	}
+
+	if v := strings.TrimSpace(params.Get("license")); v != "" {
+		var id int
+		err := q.QueryRow("SELECT id FROM licenses WHERE CAST(id AS TEXT) = ? OR UPPER(key) = ? ORDER BY id LIMIT 1",
+			v, strings.ToUpper(v)).Scan(&id)
+		if err == sql.ErrNoRows {
+			return f, newLocalizedError("eula.no_license", v)
+		}
+		if err != nil {
+			return f, err
+		}
+		if f.License, err = licenseSnapshot(q, id); err != nil {
+			return f, err
+		}
+	}
+
+	f.Type = params.Get("type")
+	if f.Type != "" && !containsString(deviceTypes, f.Type) {
+		return f, newLocalizedError("param.choice", "type", strings.Join(deviceTypes, ", "))
+	}
+	f.Status = params.Get("status")
+	if f.Status != "" && !containsString(eulaStatuses, f.Status) {
+		return f, newLocalizedError("param.choice", "status", strings.Join(eulaStatuses, ", "))
+	}
+	return f, nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// match — подходит ли устройство под условия
+func (f eulaTableQuery) match(d workplaceDevice) bool {
+	if len(f.Rooms) > 0 || f.NoRoom {
+		if d.RoomID == nil && !f.NoRoom || d.RoomID != nil && !containsID(f.Rooms, *d.RoomID) {
+			return false
+		}
+	}
+	if f.License != nil && !containsID(d.LicenseIDs, int64(f.License.ID)) {
+		return false
+	}
+	if f.Type != "" && d.Type != f.Type {
+		return false
+	}
+	switch f.Status {
+	case "":
+	case "unknown":
+		return d.Status == ""
+	default:
+		return d.Status == f.Status
+	}
+	return true
+}
+
+// describe — условия отбора для подзаголовка таблицы; "" — без условий
+func (f eulaTableQuery) describe(config workplacesConfig, lang string) string {
+	var parts []string
+	if len(f.Rooms) > 0 || f.NoRoom {
+		var names []string
+		for _, id := range f.Rooms {
+			names = append(names, config.roomName(&id, lang))
+		}
+		if f.NoRoom {
+			names = append(names, msg(lang, "room.none"))
+		}
+		parts = append(parts, msg(lang, "eula.filter.rooms", strings.Join(names, ", ")))
+	}
+	if f.License != nil {
+		parts = append(parts, msg(lang, "eula.filter.license", f.License.Key))
+	}
+	if f.Type != "" {
+		parts = append(parts, msg(lang, "eula.filter.type", msg(lang, "device.type."+f.Type)))
+	}
+	if f.Status != "" {
+		parts = append(parts, msg(lang, "eula.filter.status", msg(lang, "device.status."+f.Status)))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// emptyText — подпись для пустой таблицы на языке lang
+func (f eulaTableQuery) emptyText(config workplacesConfig, lang string) string {
+	if f.empty() {
+		return msg(lang, "eula.empty")
+	}
+	if len(f.Rooms) == 1 && !f.NoRoom && f.License == nil && f.Type == "" && f.Status == "" {
+		return msg(lang, "eula.empty_room", config.roomName(&f.Rooms[0], lang))
+	}
+	return msg(lang, "eula.empty_filter")
+}
+
+// eulaDevices — устройства, подходящие под условия
+func eulaDevices(config workplacesConfig, f eulaTableQuery) []workplaceDevice {
+	var devices []workplaceDevice
+	for _, d := range config.Devices {
+		if f.match(d) {
+			devices = append(devices, d)
+		}
+	}
+	return devices
+}
+
+// workplaceRows — устройства для отображения с подписями на языке lang
+func workplaceRows(config workplacesConfig, f eulaTableQuery, lang string) []eulaDeviceRow {
+	var rows []eulaDeviceRow
+	for _, d := range eulaDevices(config, f) {
+		mac := d.MAC
+		if mac == "" {
+			mac = "—"
+		}
+		typ, status, color := deviceLabels(d, lang)
+		rows = append(rows, eulaDeviceRow{
+			Room: config.roomName(d.RoomID, lang), Name: d.Name, MAC: mac, Type: typ, Status: status, StatusColor: color,
+		})
+	}
+	return rows
+}
+
+// deviceLabels — подписи типа и статуса устройства и цвет статуса
+func deviceLabels(d workplaceDevice, lang string) (typ, status, color string) {
+	status, color = msg(lang, "device.status.unknown"), "#64748b"
+	switch d.Status {
+	case "active":
+		status, color = msg(lang, "device.status.active"), "#22c55e"
+	case "expired":
+		status, color = msg(lang, "device.status.expired"), "#ef4444"
+	}
+
+	typ = msg(lang, "device.type.unknown")
+	if containsString(deviceTypes, d.Type) {
+		typ = msg(lang, "device.type."+d.Type)
+	}
+	return typ, status, color
+}
+
+// eulaTableJSON — устройства для format=json с ключами привязанных лицензий
+func eulaTableJSON(q workplacesQuerier, config workplacesConfig, f eulaTableQuery, lang string) ([]eulaTableDevice, error) {
+	keys := map[int64]string{}
+	rows, err := q.Query("SELECT id, key FROM licenses WHERE id IN (SELECT license_id FROM license_devices)")
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id int64
+		var key string
+		if err := rows.Scan(&id, &key); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys[id] = key
+	}
+	rows.Close()
+
+	list := []eulaTableDevice{}
+	for _, d := range eulaDevices(config, f) {
+		typ, status, _ := deviceLabels(d, lang)
+		item := eulaTableDevice{
+			ID: d.ID, RoomID: d.RoomID, Room: config.roomName(d.RoomID, lang), Name: d.Name, MAC: d.MAC,
+			Type: d.Type, TypeLabel: typ, Status: d.Status, StatusLabel: status,
+			LicenseIDs: []int64{}, LicenseKeys: []string{},
+		}
+		if item.Status == "" {
+			item.Status = "unknown"
+		}
+		for _, id := range d.LicenseIDs {
+			item.LicenseIDs = append(item.LicenseIDs, id)
+			item.LicenseKeys = append(item.LicenseKeys, keys[id])
+		}
+		list = append(list, item)
+	}
+	return list, nil
+}
+
+func handleEulaTable(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "html", "pdf", "json", "xlsx":
+	default:
+		httpError(w, r, "param.choice", 400, "format", "html, pdf, json, xlsx")
+		return
+	}
+
+	lang := requestLang(r)
+	config, err := loadWorkplaces(db)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	f, err := parseEulaTableQuery(db, r.URL.Query(), config)
+	if err != nil {
+		if isInputError(err) {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Language", lang)
+
+	if format == "json" {
+		devices, err := eulaTableJSON(db, config, f, lang)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		writeEntity(w, 200, map[string]any{
+			"filter":      f,
+			"description": f.describe(config, lang),
+			"generated":   time.Now().UTC().Format(dumpTimeFormat),
+			"devices":     devices,
+		})
+		return
+	}
+
+	rows := workplaceRows(config, f, lang)
+	empty := ""
+	if len(rows) == 0 {
+		empty = f.emptyText(config, lang)
+	}
+	subtitle := f.describe(config, lang)
+	generated := time.Now().Format(msg(lang, "fmt.datetime"))
+	title := msg(lang, "eula.title")
+	header := []string{msg(lang, "eula.col.room"), msg(lang, "col.device_name"), msg(lang, "eula.col.mac"),
+		msg(lang, "eula.col.type"), msg(lang, "eula.col.status")}
+
+	switch format {
+	case "xlsx":
+		file, err := buildEulaTableWorkbook(header, rows, lang)
+		if err != nil {
+			httpError(w, r, "export.xlsx_failed", 500)
+			return
+		}
+		defer file.Close()
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment;filename=eula-table.xlsx")
+		file.Write(w)
+
+	case "pdf":
+		doc := newDocPDF(lang, title, loadRequisites(lang))
+		if subtitle != "" {
+			doc.note(subtitle)
+		}
+		if empty != "" {
+			doc.text(empty)
+		} else {
+			cells := make([][]string, len(rows))
+			for i, d := range rows {
+				cells[i] = []string{d.Room, d.Name, d.MAC, d.Type, d.Status}
+			}
+			doc.table(header, []float64{45, 35, 40, 25, 25}, cells)
+		}
+		doc.note(msg(lang, "eula.generated") + " " + generated)
+		doc.write(w, "eula-table")
+
+	default:
+		writeEulaTableHTML(w, title, subtitle, header, rows, empty, generated, lang)
+	}
+}
+
+func writeEulaTableHTML(w http.ResponseWriter, title, subtitle string, header []string, rows []eulaDeviceRow, empty, generated, lang string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	page := `<div style="margin:60px auto;padding:40px;background:#f8f9ff;border-radius:20px;border:3px solid #6366f1;font-family:system-ui,sans-serif;">
+		<h2 style="text-align:center;color:#6366f1;font-size:28px;margin-bottom:30px;">
+			` + html.EscapeString(title) + `
+		</h2>`
+	if subtitle != "" {
+		page += `<p style="text-align:center;color:#475569;font-size:16px;margin-top:-16px;margin-bottom:24px;">` + html.EscapeString(subtitle) + `</p>`
+	}
+
+	if empty != "" {
+		page += `<p style="text-align:center;color:#64748b;font-size:18px;">` + html.EscapeString(empty) + `</p>`
+	} else {
+		page += `<table style="width:100%;border-collapse:collapse;background:white;border-radius:12px;overflow:hidden;box-shadow:0 10px 30px rgba(0,0,0,0.1);">
+			<thead><tr style="background:#6366f1;color:white;">`
+		for _, h := range header {
+			page += `
+				<th style="padding:16px;">` + html.EscapeString(h) + `</th>`
+		}
+		page += `
+			</tr></thead><tbody>`
+
+		for _, d := range rows {
+			page += fmt.Sprintf(`<tr>
+				<td style="padding:14px;font-weight:bold;">%s</td>
+				<td style="padding:14px;">%s</td>
+				<td style="padding:14px;font-family:monospace;background:#f0e6ff;">%s</td>
+				<td style="padding:14px;">%s</td>
+				<td style="padding:14px;font-weight:bold;color:%s;">%s</td>
+			</tr>`, html.EscapeString(d.Room), html.EscapeString(d.Name), html.EscapeString(d.MAC),
+				html.EscapeString(d.Type), d.StatusColor, html.EscapeString(d.Status))
+		}
+
+		page += `</tbody></table>`
+	}
+
+	page += `<p style="text-align:center;margin-top:30px;color:#64748b;font-size:14px;">
+		` + html.EscapeString(msg(lang, "eula.generated")) + ` <strong>` + generated + `</strong>
+	</p></div>`
+
+	fmt.Fprint(w, page)
+}
+
+// buildEulaTableWorkbook — та же таблица одним листом XLSX
+func buildEulaTableWorkbook(header []string, rows []eulaDeviceRow, lang string) (*excelize.File, error) {
+	f := excelize.NewFile()
+	st, err := newExportStyles(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	sheet := msg(lang, "sheet.workplaces")
+	f.SetSheetName("Sheet1", sheet)
+	writeSheetHeader(f, sheet, st, header, []float64{24, 20, 20, 14, 14})
+
+	rowIdx := 2
+	for _, d := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, rowIdx)
+		f.SetSheetRow(sheet, cell, &[]any{d.Room, d.Name, d.MAC, d.Type, d.Status})
+		rowIdx++
+	}
+	finishTable(f, sheet, len(header), rowIdx-1)
+	return f, nil
+}
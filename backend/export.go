@@ -0,0 +1,179 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === ЭКСПОРТ ЛИЦЕНЗИЙ В CSV И XLSX ===
+// GET /api/licenses/export?format=csv|xlsx — фильтры те же, что у списка
+// (см. license_query.go), плюс:
+//
+//	columns=key,description,... — какие колонки и в каком порядке (по умолчанию все)
+//	delimiter=comma|semicolon|tab — разделитель CSV; при semicolon дробная
+//	                                часть пишется через запятую, как ждёт русский Excel
+//	bom=1                         — UTF-8 BOM, чтобы Excel распознал кодировку
+//
+// Заголовки CSV — имена полей, XLSX — названия на языке запроса из синонимов
+// импорта; в обоих случаях файл (CSV или первый лист XLSX) можно загрузить
+// обратно импортом — разделитель CSV импорт определяет по строке заголовков.
+
+type licenseColumn struct {
+	Name  string
+	Kind  string  // тип ячейки в XLSX: "" — как есть, date, datetime, money
+	Width float64 // ширина колонки в XLSX
+	value func(l *License) any
+}
+
+var licenseExportColumns = []licenseColumn{
+	{"id", "", 8, func(l *License) any { return l.ID }},
+	{"key", "", 18, func(l *License) any { return l.Key }},
+	{"description", "", 40, func(l *License) any { return l.Description }},
+	{"product", "", 20, func(l *License) any { return l.Product }},
+	{"supplier", "", 20, func(l *License) any { return l.Supplier }},
+	{"cost", "money", 14, func(l *License) any { return l.Cost }},
+	{"expiry_date", "date", 13, func(l *License) any { return exportDate(l.ExpiryDate) }},
+	{"max_uses", "", 10, func(l *License) any { return l.MaxUses }},
+	{"current_uses", "", 12, func(l *License) any { return l.CurrentUses }},
+	{"activated_on", "", 30, func(l *License) any { return l.ActivatedOn }},
+	{"created_at", "datetime", 18, func(l *License) any { return l.CreatedAt }},
+}
+
+// parseExportColumns разбирает columns=; пусто — все колонки
+func parseExportColumns(v string) ([]licenseColumn, error) {
+	if strings.TrimSpace(v) == "" {
+		return licenseExportColumns, nil
+	}
+	byName := map[string]licenseColumn{}
+	for _, c := range licenseExportColumns {
+		byName[c.Name] = c
+	}
+	var cols []licenseColumn
+	for _, name := range strings.Split(v, ",") {
+		c, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			return nil, newLocalizedError("export.unknown_column", strings.TrimSpace(name))
+		}
+		cols = append(cols, c)
+	}
+	return cols, nil
+}
+
+func parseDelimiter(v string) (rune, error) {
+	switch v {
+	case "", "comma", ",":
+		return ',', nil
+	case "semicolon", ";":
+		return ';', nil
+	case "tab", "\t":
+		return '\t', nil
+	}
+	return 0, newLocalizedError("param.choice", "delimiter", "comma, semicolon, tab")
+}
+
+func handleExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format != "csv" && format != "xlsx" {
+		httpError(w, r, "param.choice", 400, "format", "csv, xlsx")
+		return
+	}
+	filter, err := parseLicenseFilter(q)
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+	columns, err := parseExportColumns(q.Get("columns"))
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+	delimiter, err := parseDelimiter(q.Get("delimiter"))
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+
+	rows, err := queryLicenses(filter)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer rows.Close()
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment;filename=licenses.csv")
+		if bom, _ := strconv.ParseBool(q.Get("bom")); bom {
+			w.Write([]byte("\xef\xbb\xbf"))
+		}
+
+		cw := csv.NewWriter(w)
+		cw.Comma = delimiter
+		header := make([]string, len(columns))
+		for i, c := range columns {
+			header[i] = c.Name
+		}
+		cw.Write(header)
+
+		record := make([]string, len(columns))
+		for rows.Next() {
+			l, err := scanLicense(rows)
+			if err != nil {
+				continue
+			}
+			for i, c := range columns {
+				record[i] = csvValue(c.value(&l), delimiter == ';')
+			}
+			cw.Write(record)
+		}
+		cw.Flush()
+		return
+	}
+
+	// === XLSX: лицензии, активации, рабочие места и сводка ===
+	f, err := buildExportWorkbook(rows, filter, columns, requestLang(r))
+	if err != nil {
+		httpError(w, r, "export.xlsx_failed", 500)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.Header().Set("Content-Disposition", "attachment;filename=licenses.xlsx")
+	f.Write(w)
+}
+
+// csvValue форматирует значение ячейки; decimalComma — «1234,5» для русского Excel
+func csvValue(v any, decimalComma bool) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case int:
+		return strconv.Itoa(x)
+	case float64:
+		s := strconv.FormatFloat(x, 'f', -1, 64)
+		if decimalComma {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s
+	case time.Time:
+		return x.Format("2006-01-02 15:04:05")
+	}
+	return fmt.Sprint(v)
+}
+
+// exportDate — ГГГГ-ММ-ДД: драйвер отдаёт колонку DATE как "2025-12-31T00:00:00Z"
+func exportDate(v string) string {
+	date, _, _ := strings.Cut(v, "T")
+	return date
+}
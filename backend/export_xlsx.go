@@ -0,0 +1,326 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// === XLSX-ВЫГРУЗКА ===
+// Книга из четырёх листов (названия и заголовки — на языке запроса):
+//
+//	Лицензии      — лицензии по фильтру; первый лист, заголовки совпадают
+//	                с синонимами импорта (см. import_columns.go), поэтому файл
+//	                загружается обратно без mapping
+//	Активации     — журнал активаций этих лицензий
+//	Рабочие места — кабинеты и устройства
+//	Сводка        — итоги по выборке
+//
+// Даты и стоимость пишутся типизированными ячейками, истёкшие и истекающие
+// в ближайшие 7 дней лицензии подсвечиваются условным форматированием.
+
+const (
+	xlsxDateFormat     = "dd.mm.yyyy"
+	xlsxDateTimeFormat = "dd.mm.yyyy hh:mm"
+	xlsxMoneyFormat    = `#,##0.00 "₽"`
+)
+
+type exportStyles struct {
+	header, date, datetime, money int
+	expired, expiring             int // условное форматирование строк
+}
+
+func newExportStyles(f *excelize.File) (exportStyles, error) {
+	var s exportStyles
+	var err error
+	custom := func(format string) *excelize.Style { return &excelize.Style{CustomNumFmt: &format} }
+
+	if s.header, err = f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true, Color: "FFFFFF"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"4F46E5"}},
+	}); err != nil {
+		return s, err
+	}
+	if s.date, err = f.NewStyle(custom(xlsxDateFormat)); err != nil {
+		return s, err
+	}
+	if s.datetime, err = f.NewStyle(custom(xlsxDateTimeFormat)); err != nil {
+		return s, err
+	}
+	if s.money, err = f.NewStyle(custom(xlsxMoneyFormat)); err != nil {
+		return s, err
+	}
+	if s.expired, err = f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFC7CE"}},
+	}); err != nil {
+		return s, err
+	}
+	s.expiring, err = f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C5700"},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"FFEB9C"}},
+	})
+	return s, err
+}
+
+// writeSheetHeader пишет строку заголовков, закрепляет её и задаёт ширины
+func writeSheetHeader(f *excelize.File, sheet string, st exportStyles, titles []string, widths []float64) {
+	header := make([]any, len(titles))
+	for i, t := range titles {
+		header[i] = t
+	}
+	f.SetSheetRow(sheet, "A1", &header)
+	last, _ := excelize.ColumnNumberToName(len(titles))
+	f.SetCellStyle(sheet, "A1", last+"1", st.header)
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+	for i, w := range widths {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(sheet, col, col, w)
+	}
+}
+
+// finishTable включает автофильтр на заполненный диапазон
+func finishTable(f *excelize.File, sheet string, cols, lastRow int) {
+	last, _ := excelize.CoordinatesToCellName(cols, max(lastRow, 2))
+	f.AutoFilter(sheet, "A1:"+last, nil)
+}
+
+// exportSummary — итоги по выгруженным лицензиям
+type exportSummary struct {
+	Total, Active, Expired, Exhausted, Expiring int
+	Cost                                        float64
+	Activations                                 int
+	BySupplier                                  map[string]*supplierTotal
+}
+
+type supplierTotal struct {
+	Count int
+	Cost  float64
+}
+
+func (s *exportSummary) add(l *License, lang string) {
+	today := time.Now().Format("2006-01-02")
+	week := time.Now().Add(7 * 24 * time.Hour).Format("2006-01-02")
+	exp := exportDate(l.ExpiryDate)
+
+	s.Total++
+	s.Cost += l.Cost
+	s.Activations += l.CurrentUses
+	switch {
+	case exp < today:
+		s.Expired++
+	case l.CurrentUses >= l.MaxUses:
+		s.Exhausted++
+	default:
+		s.Active++
+	}
+	if exp >= today && exp <= week {
+		s.Expiring++
+	}
+
+	name := l.Supplier
+	if name == "" {
+		name = msg(lang, "summary.no_supplier")
+	}
+	t := s.BySupplier[name]
+	if t == nil {
+		t = &supplierTotal{}
+		s.BySupplier[name] = t
+	}
+	t.Count++
+	t.Cost += l.Cost
+}
+
+// buildExportWorkbook собирает книгу на языке lang; rows — выборка queryLicenses(filter)
+func buildExportWorkbook(rows *sql.Rows, filter licenseFilter, columns []licenseColumn, lang string) (*excelize.File, error) {
+	f := excelize.NewFile()
+	st, err := newExportStyles(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	summary, err := writeLicensesSheet(f, st, rows, columns, lang)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := writeActivationsSheet(f, st, filter, lang); err != nil {
+		f.Close()
+		return nil, err
+	}
+	writeWorkplacesSheet(f, st, lang)
+	writeSummarySheet(f, st, summary, lang)
+
+	f.SetActiveSheet(0)
+	return f, nil
+}
+
+func writeLicensesSheet(f *excelize.File, st exportStyles, rows *sql.Rows, columns []licenseColumn, lang string) (*exportSummary, error) {
+	sheet := msg(lang, "sheet.licenses")
+	f.SetSheetName("Sheet1", sheet)
+
+	titles := make([]string, len(columns))
+	widths := make([]float64, len(columns))
+	expiryCol := ""
+	for i, c := range columns {
+		titles[i], widths[i] = msg(lang, "col."+c.Name), c.Width
+		if c.Name == "expiry_date" {
+			expiryCol, _ = excelize.ColumnNumberToName(i + 1)
+		}
+	}
+	writeSheetHeader(f, sheet, st, titles, widths)
+
+	summary := &exportSummary{BySupplier: map[string]*supplierTotal{}}
+	rowIdx := 2
+	for rows.Next() {
+		l, err := scanLicense(rows)
+		if err != nil {
+			continue
+		}
+		summary.add(&l, lang)
+
+		values := make([]any, len(columns))
+		for i, c := range columns {
+			values[i] = c.value(&l)
+			if c.Kind == "date" {
+				if t, err := time.Parse("2006-01-02", values[i].(string)); err == nil {
+					values[i] = t
+				}
+			}
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIdx)
+		f.SetSheetRow(sheet, cell, &values)
+		rowIdx++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	lastRow := rowIdx - 1
+
+	// Формат колонок задаётся диапазоном данных, чтобы не задеть заголовок
+	for i, c := range columns {
+		style := map[string]int{"date": st.date, "datetime": st.datetime, "money": st.money}[c.Kind]
+		if style == 0 || lastRow < 2 {
+			continue
+		}
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetCellStyle(sheet, col+"2", fmt.Sprintf("%s%d", col, lastRow), style)
+	}
+
+	if expiryCol != "" && lastRow >= 2 {
+		lastCol, _ := excelize.ColumnNumberToName(len(columns))
+		ref := fmt.Sprintf("A2:%s%d", lastCol, lastRow)
+		f.SetConditionalFormat(sheet, ref, []excelize.ConditionalFormatOptions{
+			{Type: "formula", Criteria: fmt.Sprintf(`AND($%s2<>"",$%s2<TODAY())`, expiryCol, expiryCol), Format: &st.expired, StopIfTrue: true},
+			{Type: "formula", Criteria: fmt.Sprintf(`AND($%s2>=TODAY(),$%s2<=TODAY()+7)`, expiryCol, expiryCol), Format: &st.expiring},
+		})
+	}
+	finishTable(f, sheet, len(columns), lastRow)
+	return summary, nil
+}
+
+// writeActivationsSheet — журнал активаций лицензий, попавших в фильтр
+func writeActivationsSheet(f *excelize.File, st exportStyles, filter licenseFilter, lang string) error {
+	sheet := msg(lang, "sheet.activations")
+	f.NewSheet(sheet)
+	writeSheetHeader(f, sheet, st,
+		[]string{msg(lang, "col.activated_at"), msg(lang, "col.key"), msg(lang, "col.description"),
+			msg(lang, "col.device_name"), msg(lang, "col.browser")},
+		[]float64{18, 18, 40, 24, 30})
+
+	rows, err := db.Query(`SELECT a.activated_at, a.license_key, COALESCE(l.description, ''),
+			COALESCE(a.device_name, ''), COALESCE(a.browser, '')
+		FROM activation_log a
+		JOIN licenses l ON l.key = a.license_key
+		WHERE l.id IN (SELECT id FROM licenses WHERE `+strings.Join(filter.where, " AND ")+`)
+		ORDER BY a.activated_at DESC`, filter.args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	rowIdx := 2
+	for rows.Next() {
+		var at time.Time
+		var key, desc, device, browser string
+		if err := rows.Scan(&at, &key, &desc, &device, &browser); err != nil {
+			continue
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, rowIdx)
+		f.SetSheetRow(sheet, cell, &[]any{at, key, desc, device, browser})
+		rowIdx++
+	}
+	if rowIdx > 2 {
+		f.SetCellStyle(sheet, "A2", fmt.Sprintf("A%d", rowIdx-1), st.datetime)
+	}
+	finishTable(f, sheet, 5, rowIdx-1)
+	return rows.Err()
+}
+
+func writeWorkplacesSheet(f *excelize.File, st exportStyles, lang string) {
+	sheet := msg(lang, "sheet.workplaces")
+	f.NewSheet(sheet)
+	writeSheetHeader(f, sheet, st,
+		[]string{msg(lang, "eula.col.room"), msg(lang, "col.device_id"), msg(lang, "col.device_name"),
+			msg(lang, "eula.col.type"), msg(lang, "eula.col.mac"), msg(lang, "eula.col.status"), "x", "y"},
+		[]float64{24, 16, 20, 14, 20, 12, 8, 8})
+
+	config := loadWorkplacesConfig()
+	rowIdx := 2
+	for _, d := range config.Devices {
+		cell, _ := excelize.CoordinatesToCellName(1, rowIdx)
+		f.SetSheetRow(sheet, cell, &[]any{
+			config.roomName(d.RoomID, lang), d.ID, d.Name, d.Type, d.MAC, d.Status, d.X, d.Y,
+		})
+		rowIdx++
+	}
+	finishTable(f, sheet, 8, rowIdx-1)
+}
+
+func writeSummarySheet(f *excelize.File, st exportStyles, s *exportSummary, lang string) {
+	sheet := msg(lang, "sheet.summary")
+	f.NewSheet(sheet)
+	f.SetColWidth(sheet, "A", "A", 32)
+	f.SetColWidth(sheet, "B", "C", 18)
+
+	rows := [][]any{
+		{msg(lang, "summary.metric"), msg(lang, "summary.value")},
+		{msg(lang, "summary.exported"), time.Now()},
+		{msg(lang, "summary.total"), s.Total},
+		{msg(lang, "summary.active"), s.Active},
+		{msg(lang, "summary.expired"), s.Expired},
+		{msg(lang, "summary.exhausted"), s.Exhausted},
+		{msg(lang, "summary.expiring"), s.Expiring},
+		{msg(lang, "summary.activations"), s.Activations},
+		{msg(lang, "summary.cost"), s.Cost},
+	}
+	for i, r := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		f.SetSheetRow(sheet, cell, &r)
+	}
+	f.SetCellStyle(sheet, "A1", "B1", st.header)
+	f.SetCellStyle(sheet, "B2", "B2", st.datetime)
+	f.SetCellStyle(sheet, "B9", "B9", st.money)
+
+	// По поставщикам — по убыванию стоимости
+	start := len(rows) + 2
+	f.SetSheetRow(sheet, fmt.Sprintf("A%d", start), &[]any{msg(lang, "col.supplier"), msg(lang, "summary.total"), msg(lang, "col.cost")})
+	f.SetCellStyle(sheet, fmt.Sprintf("A%d", start), fmt.Sprintf("C%d", start), st.header)
+
+	names := make([]string, 0, len(s.BySupplier))
+	for name := range s.BySupplier {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return s.BySupplier[names[i]].Cost > s.BySupplier[names[j]].Cost })
+	for i, name := range names {
+		t := s.BySupplier[name]
+		row := start + 1 + i
+		f.SetSheetRow(sheet, fmt.Sprintf("A%d", row), &[]any{name, t.Count, t.Cost})
+		f.SetCellStyle(sheet, fmt.Sprintf("C%d", row), fmt.Sprintf("C%d", row), st.money)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// === ЛОКАЛИЗАЦИЯ ===
+// Сообщения API, заголовки выгрузок и служебные надписи документов берутся из
+// каталогов (i18n_ru.go, i18n_en.go) по идентификатору. Язык запроса: ?lang=,
+// затем Accept-Language (с учётом q), иначе русский. Нет перевода — берётся
+// русский текст, нет и его — сам идентификатор.
+
+const defaultLang = "ru"
+
+var catalogs = map[string]map[string]string{
+	"ru": messagesRU,
+	"en": messagesEN,
+}
+
+// supportedLangs — языки каталогов в постоянном порядке (для миграций и отчётов)
+var supportedLangs = []string{"ru", "en"}
+
+// normalizeLang — "en-US" → "en"; пустая строка, если язык не поддерживается
+func normalizeLang(tag string) string {
+	base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+	if _, ok := catalogs[base]; ok {
+		return base
+	}
+	return ""
+}
+
+func requestLang(r *http.Request) string {
+	if r == nil {
+		return defaultLang
+	}
+	if lang := normalizeLang(r.URL.Query().Get("lang")); lang != "" {
+		return lang
+	}
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if lang := normalizeLang(tag); lang != "" && q > bestQ {
+			best, bestQ = lang, q
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return defaultLang
+}
+
+// msg — сообщение id на языке lang; args подставляются через fmt.Sprintf
+func msg(lang, id string, args ...any) string {
+	text, ok := catalogs[lang][id]
+	if !ok {
+		text, ok = catalogs[defaultLang][id]
+	}
+	if !ok {
+		text = id
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// tr — сообщение на языке запроса
+func tr(r *http.Request, id string, args ...any) string {
+	return msg(requestLang(r), id, args...)
+}
+
+// httpError — http.Error с сообщением из каталога
+func httpError(w http.ResponseWriter, r *http.Request, id string, code int, args ...any) {
+	http.Error(w, tr(r, id, args...), code)
+}
+
+// localizedError — ошибка проверки входных данных из функций, которым запрос
+// не передаётся; текст на нужном языке даёт errorText
+type localizedError struct {
+	id   string
+	args []any
+}
+
+func newLocalizedError(id string, args ...any) error {
+	return &localizedError{id, args}
+}
+
+func (e *localizedError) Error() string {
+	return e.text(defaultLang)
+}
+
+// Unwrap — ошибка среди аргументов, которую localizedError оборачивает
+func (e *localizedError) Unwrap() error {
+	for _, a := range e.args {
+		if err, ok := a.(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+// text — сообщение на языке lang; вложенные ошибки переводятся тем же языком
+func (e *localizedError) text(lang string) string {
+	args := make([]any, len(e.args))
+	for i, a := range e.args {
+		if err, ok := a.(error); ok {
+			a = errorLangText(lang, err)
+		}
+		args[i] = a
+	}
+	return msg(lang, e.id, args...)
+}
+
+// errorText — текст ошибки на языке запроса (для localizedError) или как есть
+func errorText(r *http.Request, err error) string {
+	return errorLangText(requestLang(r), err)
+}
+
+func errorLangText(lang string, err error) string {
+	var le *localizedError
+	if errors.As(err, &le) {
+		return le.text(lang)
+	}
+	return err.Error()
+}
+
+// isInputError — ошибка вызвана данными запроса, а не БД
+func isInputError(err error) bool {
+	var le *localizedError
+	return errors.As(err, &le)
+}
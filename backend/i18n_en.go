@@ -0,0 +1,202 @@
+package main
+
+// === КАТАЛОГ СООБЩЕНИЙ: АНГЛИЙСКИЙ ===
+// Отсутствующие здесь идентификаторы берутся из messagesRU
+var messagesEN = map[string]string{
+	"err.db":             "Database error",
+	"err.save":           "Save error",
+	"err.create":         "Create error",
+	"err.method":         "Method not allowed",
+	"err.not_found":      "Not found",
+	"err.invalid_json":   "Invalid JSON",
+	"err.id_required":    "ID required",
+	"err.ip_required":    "IP required",
+	"err.bad_ip":         "Invalid IP: %s",
+	"err.file_required":  "File is required",
+	"err.file_too_large": "File exceeds the size limit (%d bytes)",
+	"err.origin":         "Origin not allowed",
+	"err.client_cert":    "Client certificate required",
+
+	"param.choice":        "%s: allowed values are %s",
+	"param.date":          "%s: date expected in YYYY-MM-DD format",
+	"param.version":       "%s: version number expected",
+	"param.invalid":       "Invalid %s",
+	"filter.sort_column":  "sort: unknown column %q",
+	"settings.no_default": "No default value for this key",
+
+	"validate.not_found":    "Key not found",
+	"validate.expired":      "License expired",
+	"validate.limit":        "Activation limit reached",
+	"validate.banned":       "Access denied",
+	"validate.locked_out":   "Too many failed attempts",
+	"validate.rate_limited": "Too many requests",
+
+	"accept.none":          "No documents to accept",
+	"accept.not_required":  "Document %q does not require acceptance",
+	"accept.not_published": "Version %d of document %s is not published",
+
+	"export.unknown_column":      "Unknown column %q",
+	"export.xlsx_failed":         "Failed to build XLSX",
+	"import.parse_failed":        "Could not parse file: %s",
+	"import.empty_file":          "empty file",
+	"import.empty_sheet":         "empty sheet",
+	"import.sheet_not_found":     "sheet %q not found",
+	"import.missing_columns":     "missing columns: %s",
+	"import.mapping_field":       "unknown field %q in mapping",
+	"import.mapping_column":      "column %q from mapping not found",
+	"import.job_not_found":       "Job not found",
+	"import.report_not_found":    "Report not found",
+	"import.bad_key":             "Invalid key %q",
+	"import.empty_description":   "Description is empty",
+	"import.no_expiry":           "Expiry date is missing",
+	"import.bad_date":            "Invalid date %q, expected YYYY-MM-DD or DD.MM.YYYY",
+	"import.date_invalid":        "invalid date %q",
+	"import.not_number":          "Not a number: %q",
+	"import.min_one":             "Must be at least 1",
+	"import.negative_cost":       "Cost cannot be negative",
+	"import.negative":            "Cannot be negative",
+	"import.over_limit":          "Exceeds the activation limit (%d)",
+	"import.below_uses":          "Less than the number of activations (%d)",
+	"import.duplicate_key":       "Duplicate key (row %d)",
+	"import.key_exists":          "Key already exists",
+	"import.key_skipped":         "Key already exists, skipped",
+	"import.db_error":            "Database error: %s",
+	"import.write_error":         "Write error: %s",
+	"import.batch_error":         "Failed to write a batch of %d rows: %s",
+	"import.interrupted":         "Import interrupted: %s",
+	"import.atomic_limit":        "All-or-nothing import is limited to %d rows; split the file or use mode=partial",
+	"import.summary.dry_run":     "Check: %d of %d rows valid, %d errors",
+	"import.summary.failed":      "Import cancelled: %d errors, nothing added",
+	"import.summary.interrupted": "Import interrupted after %d rows. Added: %d, updated: %d, skipped: %d",
+	"import.summary.done":        "Added: %d, updated: %d, skipped: %d",
+	"import.report.sheet":        "Errors",
+	"import.report.row":          "Row",
+	"import.report.field":        "Field",
+	"import.report.error":        "Error",
+	"restore.failed":             "Restore failed: %s",
+	"restore.at":                 "%s: %s",
+	"restore.bad_dump":           "empty or malformed dump: %s",
+	"restore.no_header":          "dump header is missing",
+	"restore.record":             "record %d: %s",
+	"restore.record_type":        "record %d (%s): %s",
+	"restore.unknown_format":     "unknown format %q",
+	"restore.schema_version":     "schema version %d is not supported (up to %d)",
+	"restore.unknown_record":     "unknown record type %q",
+	"restore.empty_key":          "empty key",
+	"restore.empty_setting":      "empty setting key",
+	"restore.unknown_doc":        "unknown document %q",
+	"restore.bad_version":        "%s: invalid version %d (%s)",
+	"restore.bad_locale":         "%s: unknown language %q",
+	"restore.bad_doc_number":     "%s: invalid number %d (%q)",
+	"restore.bad_room":           "rooms[%d]: invalid room",
+	"restore.bad_device":         "devices[%d]: invalid device or room",
+	"restore.no_license":         "license %q not found",
+	"restore.no_device":          "device %d not found",
+
+	"audit.verify.prev_hash": "prev_hash does not match the hash of the previous record: a record was deleted or inserted",
+	"audit.verify.hash":      "hash does not match the content: the record was modified",
+	"audit.verify.truncated": "the last record does not match the stored chain head: records were deleted from the end",
+
+	"workplaces.room_name":        "Room name must not be empty",
+	"workplaces.unknown_room":     "Room %d not found",
+	"workplaces.version_required": "Record version is required (version)",
+	"workplaces.conflict":         "Record was changed by someone else: your version is %d, current is %d. Reload and retry",
+	"workplaces.stale_revision":   "Workplaces were changed by someone else: your revision is %d, current is %d. Reload and retry",
+	"workplaces.bad_mac":          "Invalid MAC address: %s",
+	"workplaces.duplicate_mac":    "MAC %s is already used by device %d",
+	"discovery.unknown_format":    "Unknown format: expected dhcpd or dnsmasq leases, ip neigh output or an ARP table",
+	"discovery.bad_mac":           "invalid MAC %s",
+	"discovery.bad_line":          "line does not look like a dnsmasq lease",
+	"discovery.ids_required":      "No devices selected (ids)",
+	"discovery.not_found":         "Device %d is not in the queue",
+	"discovery.not_pending":       "Device %d was already handled",
+	"license_devices.limit":       "License is already linked to %d of %d devices",
+
+	"doc.unknown":             "Unknown document",
+	"doc.unknown_type":        "Unknown document type: invoice, certificate or eula",
+	"doc.not_published":       "No published version of the document",
+	"doc.version_not_found":   "Version not found",
+	"doc.bad_version":         "Invalid version number",
+	"doc.published_immutable": "A published version cannot be changed — create a new one",
+	"doc.body_required":       "body is required",
+	"doc.template_error":      "Template error: %s",
+	"doc.render_error":        "Document template error: %v",
+	"doc.build_error":         "Failed to build document",
+	"doc.pdf_error":           "Failed to build PDF",
+	"doc.tin":                 "TIN",
+	"doc.ogrn":                "PSRN",
+	"doc.page":                "Page %d of {nb}",
+
+	"doc.title.eula_text":                "End User License Agreement (EULA)",
+	"doc.title.privacy_policy":           "Privacy Policy",
+	"doc.title.offer_text":               "Public Offer",
+	"doc.title.payment_text":             "Payment Order",
+	"doc.title.invoice_text":             "Delivery Note",
+	"doc.title.license_invoice_text":     "Delivery Note",
+	"doc.title.license_certificate_text": "License Certificate",
+	"doc.title.license_eula_text":        "End User License Agreement (EULA)",
+
+	"fmt.date":      "Jan 2, 2006",
+	"fmt.datetime":  "Jan 2, 2006 at 15:04",
+	"fmt.thousands": ",",
+	"fmt.decimal":   ".",
+
+	"eula.title":            "Appendix: Active Workplaces",
+	"eula.col.room":         "Room",
+	"eula.col.mac":          "MAC address",
+	"eula.col.type":         "Type",
+	"eula.col.status":       "Status",
+	"eula.generated":        "Generated:",
+	"eula.empty":            "No active devices",
+	"eula.empty_room":       "No active devices in room “%s”",
+	"eula.empty_filter":     "No devices match the conditions",
+	"eula.filter.rooms":     "Rooms: %s",
+	"eula.filter.license":   "License: %s",
+	"eula.filter.type":      "Type: %s",
+	"eula.filter.status":    "Status: %s",
+	"eula.no_room":          "Room %d not found",
+	"eula.no_license":       "License %s not found",
+	"room.none":             "No room",
+	"room.numbered":         "Room %d",
+	"device.status.active":  "Active",
+	"device.status.expired": "Expired",
+	"device.status.unknown": "Not checked",
+	"device.type.desktop":   "Desktop",
+	"device.type.laptop":    "Laptop",
+	"device.type.tablet":    "Tablet",
+	"device.type.server":    "Server",
+	"device.type.unknown":   "Unknown",
+
+	"sheet.licenses":    "Licenses",
+	"sheet.activations": "Activations",
+	"sheet.workplaces":  "Workplaces",
+	"sheet.summary":     "Summary",
+
+	"col.id":           "ID",
+	"col.key":          "Key",
+	"col.description":  "Description",
+	"col.product":      "Product",
+	"col.supplier":     "Supplier",
+	"col.cost":         "Cost",
+	"col.expiry_date":  "Expiry date",
+	"col.max_uses":     "Max uses",
+	"col.current_uses": "Current uses",
+	"col.activated_on": "Activated on",
+	"col.created_at":   "Created at",
+	"col.activated_at": "Activated at",
+	"col.device_name":  "Device",
+	"col.browser":      "Browser",
+	"col.device_id":    "Device ID",
+
+	"summary.metric":      "Metric",
+	"summary.value":       "Value",
+	"summary.exported":    "Exported",
+	"summary.total":       "Licenses",
+	"summary.active":      "Active",
+	"summary.expired":     "Expired",
+	"summary.exhausted":   "Exhausted",
+	"summary.expiring":    "Expiring within 7 days",
+	"summary.activations": "Activations",
+	"summary.cost":        "Total cost",
+	"summary.no_supplier": "No supplier",
+}
@@ -0,0 +1,213 @@
+package main
+
+// === КАТАЛОГ СООБЩЕНИЙ: РУССКИЙ ===
+// Язык по умолчанию: здесь должны быть все идентификаторы (см. i18n.go)
+var messagesRU = map[string]string{
+	// Общие ошибки API
+	"err.db":             "Ошибка базы данных",
+	"err.save":           "Ошибка сохранения",
+	"err.create":         "Ошибка создания",
+	"err.method":         "Метод не поддерживается",
+	"err.not_found":      "Не найдено",
+	"err.invalid_json":   "Некорректный JSON",
+	"err.id_required":    "Не указан ID",
+	"err.ip_required":    "Не указан IP",
+	"err.bad_ip":         "Некорректный IP: %s",
+	"err.file_required":  "Файл обязателен",
+	"err.file_too_large": "Файл больше допустимого размера (%d байт)",
+	"err.origin":         "Origin не разрешён",
+	"err.client_cert":    "Требуется клиентский сертификат",
+
+	// Параметры запроса
+	"param.choice":        "%s: допустимые значения — %s",
+	"param.date":          "%s: дата в формате ГГГГ-ММ-ДД",
+	"param.version":       "%s: номер редакции",
+	"param.invalid":       "Некорректный %s",
+	"filter.sort_column":  "sort: неизвестная колонка %q",
+	"settings.no_default": "Нет значения по умолчанию для ключа",
+
+	// Проверка ключа
+	"validate.not_found":    "Ключ не найден",
+	"validate.expired":      "Срок истёк",
+	"validate.limit":        "Лимит исчерпан",
+	"validate.banned":       "Доступ запрещён",
+	"validate.locked_out":   "Слишком много неудачных попыток",
+	"validate.rate_limited": "Слишком много запросов",
+
+	// Принятие документов
+	"accept.none":          "Не указаны принимаемые документы",
+	"accept.not_required":  "Документ %q не требует принятия",
+	"accept.not_published": "Редакция %d документа %s не опубликована",
+
+	// Экспорт, импорт, восстановление
+	"export.unknown_column":      "Неизвестная колонка %q",
+	"export.xlsx_failed":         "Ошибка формирования XLSX",
+	"import.parse_failed":        "Не удалось разобрать файл: %s",
+	"import.empty_file":          "пустой файл",
+	"import.empty_sheet":         "пустой лист",
+	"import.sheet_not_found":     "лист %q не найден",
+	"import.missing_columns":     "нет колонок: %s",
+	"import.mapping_field":       "неизвестное поле %q в mapping",
+	"import.mapping_column":      "колонка %q из mapping не найдена",
+	"import.job_not_found":       "Задача не найдена",
+	"import.report_not_found":    "Отчёт не найден",
+	"import.bad_key":             "Некорректный ключ %q",
+	"import.empty_description":   "Пустое описание",
+	"import.no_expiry":           "Не указана дата окончания",
+	"import.bad_date":            "Некорректная дата %q, ожидается ГГГГ-ММ-ДД или ДД.ММ.ГГГГ",
+	"import.date_invalid":        "некорректная дата %q",
+	"import.not_number":          "Не число: %q",
+	"import.min_one":             "Должно быть не меньше 1",
+	"import.negative_cost":       "Стоимость не может быть отрицательной",
+	"import.negative":            "Не может быть отрицательным",
+	"import.over_limit":          "Больше лимита активаций (%d)",
+	"import.below_uses":          "Меньше числа активаций (%d)",
+	"import.duplicate_key":       "Ключ повторяется (строка %d)",
+	"import.key_exists":          "Ключ уже существует",
+	"import.key_skipped":         "Ключ уже существует — пропущено",
+	"import.db_error":            "Ошибка БД: %s",
+	"import.write_error":         "Ошибка записи: %s",
+	"import.batch_error":         "Ошибка записи пакета из %d строк: %s",
+	"import.interrupted":         "Импорт прерван: %s",
+	"import.atomic_limit":        "Импорт «всё или ничего» — не больше %d строк; разбейте файл или используйте mode=partial",
+	"import.summary.dry_run":     "Проверка: корректных строк %d из %d, ошибок %d",
+	"import.summary.failed":      "Импорт отменён: ошибок %d, ничего не добавлено",
+	"import.summary.interrupted": "Импорт прерван после %d строк. Добавлено: %d, обновлено: %d, пропущено: %d",
+	"import.summary.done":        "Добавлено: %d, обновлено: %d, пропущено: %d",
+	"import.report.sheet":        "Ошибки",
+	"import.report.row":          "Строка",
+	"import.report.field":        "Поле",
+	"import.report.error":        "Ошибка",
+	"restore.failed":             "Не удалось восстановить: %s",
+	"restore.at":                 "%s: %s",
+	"restore.bad_dump":           "пустой или некорректный дамп: %s",
+	"restore.no_header":          "нет заголовка дампа",
+	"restore.record":             "запись %d: %s",
+	"restore.record_type":        "запись %d (%s): %s",
+	"restore.unknown_format":     "неизвестный формат %q",
+	"restore.schema_version":     "версия схемы %d не поддерживается (поддерживается до %d)",
+	"restore.unknown_record":     "неизвестный тип записи %q",
+	"restore.empty_key":          "пустой ключ",
+	"restore.empty_setting":      "пустой ключ настройки",
+	"restore.unknown_doc":        "неизвестный документ %q",
+	"restore.bad_version":        "%s: некорректная редакция %d (%s)",
+	"restore.bad_locale":         "%s: неизвестный язык %q",
+	"restore.bad_doc_number":     "%s: некорректный номер %d (%q)",
+	"restore.bad_room":           "rooms[%d]: некорректный кабинет",
+	"restore.bad_device":         "devices[%d]: некорректное устройство или кабинет",
+	"restore.no_license":         "нет лицензии %q",
+	"restore.no_device":          "нет устройства %d",
+
+	// Журнал аудита
+	"audit.verify.prev_hash": "prev_hash не совпадает с хэшем предыдущей записи — запись удалена или вставлена",
+	"audit.verify.hash":      "хэш не совпадает с содержимым — запись изменена",
+	"audit.verify.truncated": "последняя запись не совпадает с сохранённой головой цепочки — записи в конце удалены",
+
+	// Рабочие места
+	"workplaces.room_name":        "Название кабинета не может быть пустым",
+	"workplaces.unknown_room":     "Кабинет %d не найден",
+	"workplaces.version_required": "Не указана версия записи (version)",
+	"workplaces.conflict":         "Запись уже изменена: у вас версия %d, текущая — %d. Обновите данные",
+	"workplaces.stale_revision":   "Схема рабочих мест уже изменена: у вас ревизия %d, текущая — %d. Обновите данные",
+	"workplaces.bad_mac":          "Некорректный MAC-адрес: %s",
+	"workplaces.duplicate_mac":    "MAC %s уже указан у устройства %d",
+	"discovery.unknown_format":    "Не удалось определить формат: ожидаются аренды dhcpd или dnsmasq, вывод ip neigh или таблица ARP",
+	"discovery.bad_mac":           "некорректный MAC %s",
+	"discovery.bad_line":          "строка не похожа на аренду dnsmasq",
+	"discovery.ids_required":      "Не выбраны устройства (ids)",
+	"discovery.not_found":         "Устройство %d не найдено в очереди",
+	"discovery.not_pending":       "Устройство %d уже обработано",
+	"license_devices.limit":       "Лицензия уже привязана к %d устройствам из %d",
+
+	// Документы
+	"doc.unknown":             "Неизвестный документ",
+	"doc.unknown_type":        "Неизвестный тип документа: invoice, certificate или eula",
+	"doc.not_published":       "Редакция документа не опубликована",
+	"doc.version_not_found":   "Редакция не найдена",
+	"doc.bad_version":         "Некорректный номер редакции",
+	"doc.published_immutable": "Опубликованная редакция не изменяется — создайте новую",
+	"doc.body_required":       "body обязателен",
+	"doc.template_error":      "Ошибка в шаблоне %s",
+	"doc.render_error":        "Ошибка в шаблоне документа: %v",
+	"doc.build_error":         "Ошибка формирования документа",
+	"doc.pdf_error":           "Ошибка формирования PDF",
+	"doc.tin":                 "ИНН",
+	"doc.ogrn":                "ОГРН",
+	"doc.page":                "Страница %d из {nb}",
+
+	"doc.title.eula_text":                "Лицензионное соглашение (EULA)",
+	"doc.title.privacy_policy":           "Политика конфиденциальности",
+	"doc.title.offer_text":               "Публичная оферта",
+	"doc.title.payment_text":             "Платёжное поручение",
+	"doc.title.invoice_text":             "Товарная накладная",
+	"doc.title.license_invoice_text":     "Товарная накладная",
+	"doc.title.license_certificate_text": "Лицензионный сертификат",
+	"doc.title.license_eula_text":        "Лицензионное соглашение (EULA)",
+
+	// Форматы
+	"fmt.date":      "02.01.2006",
+	"fmt.datetime":  "02.01.2006 в 15:04",
+	"fmt.thousands": "\u00a0", // неразрывный пробел
+	"fmt.decimal":   ",",
+
+	// Рабочие места и таблица EULA
+	"eula.title":            "Приложение: Активные рабочие места",
+	"eula.col.room":         "Кабинет",
+	"eula.col.mac":          "MAC-адрес",
+	"eula.col.type":         "Тип",
+	"eula.col.status":       "Статус",
+	"eula.generated":        "Сформировано:",
+	"eula.empty":            "Нет активных устройств",
+	"eula.empty_room":       "В кабинете «%s» нет активных устройств",
+	"eula.empty_filter":     "Нет устройств, подходящих под условия",
+	"eula.filter.rooms":     "Кабинеты: %s",
+	"eula.filter.license":   "Лицензия: %s",
+	"eula.filter.type":      "Тип: %s",
+	"eula.filter.status":    "Статус: %s",
+	"eula.no_room":          "Кабинет %d не найден",
+	"eula.no_license":       "Лицензия %s не найдена",
+	"room.none":             "Без кабинета",
+	"room.numbered":         "Кабинет %d",
+	"device.status.active":  "Активен",
+	"device.status.expired": "Истёк",
+	"device.status.unknown": "Не проверен",
+	"device.type.desktop":   "ПК",
+	"device.type.laptop":    "Ноутбук",
+	"device.type.tablet":    "Планшет",
+	"device.type.server":    "Сервер",
+	"device.type.unknown":   "Неизвестно",
+
+	// XLSX-выгрузка: листы и заголовки (заголовки лицензий — синонимы импорта)
+	"sheet.licenses":    "Лицензии",
+	"sheet.activations": "Активации",
+	"sheet.workplaces":  "Рабочие места",
+	"sheet.summary":     "Сводка",
+
+	"col.id":           "ID",
+	"col.key":          "Ключ",
+	"col.description":  "Описание",
+	"col.product":      "Продукт",
+	"col.supplier":     "Поставщик",
+	"col.cost":         "Стоимость",
+	"col.expiry_date":  "Срок действия",
+	"col.max_uses":     "Лимит активаций",
+	"col.current_uses": "Активаций",
+	"col.activated_on": "Рабочие места",
+	"col.created_at":   "Создана",
+	"col.activated_at": "Дата активации",
+	"col.device_name":  "Устройство",
+	"col.browser":      "Браузер",
+	"col.device_id":    "ID устройства",
+
+	"summary.metric":      "Показатель",
+	"summary.value":       "Значение",
+	"summary.exported":    "Выгружено",
+	"summary.total":       "Лицензий",
+	"summary.active":      "Активных",
+	"summary.expired":     "Истёкших",
+	"summary.exhausted":   "Исчерпанных",
+	"summary.expiring":    "Истекают в ближайшие 7 дней",
+	"summary.activations": "Активаций",
+	"summary.cost":        "Общая стоимость",
+	"summary.no_supplier": "Без поставщика",
+}
@@ -0,0 +1,772 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+)
+
+// === ИМПОРТ CSV + XLSX ===
+// POST /api/licenses/import (multipart: file, format=csv|xlsx)
+//
+//	dry_run=1    — только разбор и проверка, без записи в БД
+//	mode=atomic  — всё или ничего: при любой ошибке не пишется ни одна строка;
+//	               не больше LM_IMPORT_BATCH_SIZE строк (одна транзакция держит
+//	               блокировку записи, и проверки ключей ждали бы её конца)
+//	mode=partial — (по умолчанию) плохие строки пропускаются, запись идёт
+//	               пакетами по LM_IMPORT_BATCH_SIZE строк в транзакции
+//	on_conflict=skip|overwrite|fail — что делать, если ключ из файла уже есть в БД
+//	sheet=Имя листа, mapping={"Заголовок": "поле"} — см. import_columns.go
+//	async=1      — сразу ответить 202 с job_id; ход импорта —
+//	               GET /api/licenses/import/jobs/{id}
+//
+// Размер файла ограничен LM_IMPORT_MAX_BYTES. Отчёт по строкам доступен по
+// /api/licenses/import/report/{id}?format=csv|xlsx|json
+func handleImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	// Большой файл грузится дольше обычных таймаутов сервера
+	rc := http.NewResponseController(w)
+	rc.SetReadDeadline(time.Now().Add(cfg.ImportTimeout))
+	rc.SetWriteDeadline(time.Now().Add(cfg.ImportTimeout))
+
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.ImportMaxBytes)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httpError(w, r, "err.file_too_large", http.StatusRequestEntityTooLarge, cfg.ImportMaxBytes)
+			return
+		}
+		httpError(w, r, "err.file_required", 400)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	format := r.FormValue("format")
+	if format != "csv" && format != "xlsx" {
+		httpError(w, r, "param.choice", 400, "format", "csv, xlsx")
+		return
+	}
+
+	mode := r.FormValue("mode")
+	if mode == "" {
+		mode = importModePartial
+	}
+	if mode != importModePartial && mode != importModeAtomic {
+		httpError(w, r, "param.choice", 400, "mode", "partial, atomic")
+		return
+	}
+	dryRun, _ := strconv.ParseBool(r.FormValue("dry_run"))
+	async, _ := strconv.ParseBool(r.FormValue("async"))
+
+	onConflict := r.FormValue("on_conflict")
+	if onConflict == "" {
+		onConflict = conflictSkip
+	}
+	if onConflict != conflictSkip && onConflict != conflictOverwrite && onConflict != conflictFail {
+		httpError(w, r, "param.choice", 400, "on_conflict", "skip, overwrite, fail")
+		return
+	}
+
+	parseOpts, err := parseImportOptions(r)
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+
+	path, size, err := saveUpload(r, "file")
+	if err != nil {
+		httpError(w, r, "err.file_required", 400)
+		return
+	}
+	src, err := openImportSource(path, format, parseOpts.Sheet)
+	if err != nil {
+		os.Remove(path)
+		httpError(w, r, "import.parse_failed", 400, errorText(r, err))
+		return
+	}
+	columns, err := importSourceColumns(src, parseOpts.Mapping)
+	if err != nil {
+		src.Close()
+		os.Remove(path)
+		httpError(w, r, "import.parse_failed", 400, errorText(r, err))
+		return
+	}
+
+	opts := importOptions{Mode: mode, DryRun: dryRun, OnConflict: onConflict}
+
+	if async {
+		job := newImportJob(format, size)
+		// Запрос закончится раньше задачи: для аудита нужна его копия
+		jobReq := r.Clone(context.Background())
+		goBackground(func(ctx context.Context) {
+			defer os.Remove(path)
+			defer src.Close()
+			job.run(ctx, jobReq, src, columns, opts)
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{
+			"job_id":     job.id,
+			"status_url": "/api/licenses/import/jobs/" + job.id,
+		})
+		return
+	}
+
+	defer os.Remove(path)
+	defer src.Close()
+
+	report, err := runImport(r.Context(), r, src, columns, opts, nil)
+	saveImportReport(report)
+	if err != nil {
+		log.Printf("Импорт %s прерван: %v", report.ID, err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Failed {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	json.NewEncoder(w).Encode(report.result())
+}
+
+const (
+	importModePartial = "partial"
+	importModeAtomic  = "atomic"
+
+	// Политика для ключей, которые уже есть в БД
+	conflictSkip      = "skip"      // строка пропускается
+	conflictOverwrite = "overwrite" // поля из файла перезаписывают существующую лицензию
+	conflictFail      = "fail"      // импорт целиком отменяется
+)
+
+type importOptions struct {
+	Mode       string
+	DryRun     bool
+	OnConflict string
+}
+
+// allOrNothing — запись одной транзакцией: atomic или отмена всего
+// импорта при конфликте ключей
+func (o importOptions) allOrNothing() bool {
+	return o.Mode == importModeAtomic || o.OnConflict == conflictFail
+}
+
+type ImportLicense struct {
+	Key         string // пусто — ключ будет сгенерирован
+	Description string
+	ExpiryDate  string
+	MaxUses     int
+	Cost        float64
+	Supplier    string
+	CurrentUses int
+	ActivatedOn string
+	Product     string
+
+	// Present — колонки, которые есть в файле; при overwrite
+	// обновляются только они
+	Present map[string]bool
+}
+
+// importRow — строка файла после разбора; Row — номер строки в файле (заголовок = 1)
+type importRow struct {
+	Row    int
+	Item   *ImportLicense
+	Errors []importRowError
+}
+
+type importRowError struct {
+	Row   int    `json:"row"`
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+func (r *importRow) fail(field, msg string) {
+	r.Errors = append(r.Errors, importRowError{Row: r.Row, Field: field, Error: msg})
+}
+
+// Колонки импорта в порядке по умолчанию (для XLSX без заголовков: A, B, C, ...)
+var importColumns = []string{
+	"description", "expiry_date", "max_uses",
+	"key", "cost", "supplier", "current_uses", "activated_on", "product",
+}
+
+// Обязательные колонки CSV
+var importRequiredColumns = []string{"description", "expiry_date"}
+
+// Ключи вида generateKey (A-F, 0-9, дефис) и ключи поставщиков — латиница, цифры, дефисы
+var importKeyPattern = regexp.MustCompile(`^[A-Z0-9][A-Z0-9-]{3,63}$`)
+
+// buildImportRow разбирает и проверяет значения одной строки; ошибки — на языке lang
+func buildImportRow(lang string, rowNum int, values map[string]string) importRow {
+	row := importRow{Row: rowNum, Item: &ImportLicense{MaxUses: 5, Present: map[string]bool{}}}
+	item := row.Item
+	for col := range values {
+		item.Present[col] = true
+	}
+
+	if v := strings.ToUpper(strings.TrimSpace(values["key"])); v != "" {
+		if importKeyPattern.MatchString(v) {
+			item.Key = v
+		} else {
+			row.fail("key", msg(lang, "import.bad_key", v))
+		}
+	}
+
+	item.Description = strings.TrimSpace(values["description"])
+	if item.Description == "" {
+		row.fail("description", msg(lang, "import.empty_description"))
+	}
+
+	item.ExpiryDate = strings.TrimSpace(values["expiry_date"])
+	if item.ExpiryDate == "" {
+		row.fail("expiry_date", msg(lang, "import.no_expiry"))
+	} else if d, err := normalizeImportDate(item.ExpiryDate); err != nil {
+		row.fail("expiry_date", msg(lang, "import.bad_date", item.ExpiryDate))
+	} else {
+		item.ExpiryDate = d
+	}
+
+	if v := strings.TrimSpace(values["max_uses"]); v != "" {
+		n, err := strconv.Atoi(v)
+		switch {
+		case err != nil:
+			row.fail("max_uses", msg(lang, "import.not_number", v))
+		case n < 1:
+			row.fail("max_uses", msg(lang, "import.min_one"))
+		default:
+			item.MaxUses = n
+		}
+	}
+
+	if v := strings.TrimSpace(values["cost"]); v != "" {
+		// «1 234,50» из русского Excel
+		norm := strings.NewReplacer(" ", "", "\u00a0", "", ",", ".").Replace(v)
+		f, err := strconv.ParseFloat(norm, 64)
+		switch {
+		case err != nil:
+			row.fail("cost", msg(lang, "import.not_number", v))
+		case f < 0:
+			row.fail("cost", msg(lang, "import.negative_cost"))
+		default:
+			item.Cost = f
+		}
+	}
+
+	if v := strings.TrimSpace(values["current_uses"]); v != "" {
+		n, err := strconv.Atoi(v)
+		switch {
+		case err != nil:
+			row.fail("current_uses", msg(lang, "import.not_number", v))
+		case n < 0:
+			row.fail("current_uses", msg(lang, "import.negative"))
+		// Строку с ключом, которая может обновить существующую лицензию,
+		// сверяет с сохранённым лимитом checkKey
+		case (item.Present["max_uses"] || item.Key == "") && n > item.MaxUses:
+			row.fail("current_uses", msg(lang, "import.over_limit", item.MaxUses))
+		default:
+			item.CurrentUses = n
+		}
+	}
+
+	item.Supplier = strings.TrimSpace(values["supplier"])
+	item.ActivatedOn = strings.TrimSpace(values["activated_on"])
+	item.Product = strings.TrimSpace(values["product"])
+	return row
+}
+
+func isBlankRow(cells []string) bool {
+	for _, c := range cells {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// === ОТЧЁТ ОБ ИМПОРТЕ ===
+type importReport struct {
+	ID          string           `json:"id"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Mode        string           `json:"mode"`
+	OnConflict  string           `json:"on_conflict"`
+	DryRun      bool             `json:"dry_run"`
+	Failed      bool             `json:"failed"`                // импорт отменён целиком
+	Interrupted bool             `json:"interrupted,omitempty"` // остановлен на середине (отмена запроса, остановка сервера)
+	Total       int              `json:"total"`
+	Valid       int              `json:"valid"`
+	Imported    int              `json:"imported"`
+	Updated     int              `json:"updated"`
+	Skipped     int              `json:"skipped"`
+	Errors      []importRowError `json:"errors"`
+
+	lang string // язык запроса: на нём ошибки строк и итоговое сообщение
+}
+
+func (rep *importReport) message() string {
+	switch {
+	case rep.DryRun:
+		return msg(rep.lang, "import.summary.dry_run", rep.Valid, rep.Total, len(rep.Errors))
+	case rep.Failed:
+		return msg(rep.lang, "import.summary.failed", len(rep.Errors))
+	case rep.Interrupted:
+		return msg(rep.lang, "import.summary.interrupted", rep.Total, rep.Imported, rep.Updated, rep.Skipped)
+	}
+	return msg(rep.lang, "import.summary.done", rep.Imported, rep.Updated, rep.Skipped)
+}
+
+// result — ответ ручки импорта и итог фоновой задачи
+func (rep *importReport) result() map[string]any {
+	return map[string]any{
+		"success":     !rep.Failed && !rep.Interrupted,
+		"dry_run":     rep.DryRun,
+		"mode":        rep.Mode,
+		"on_conflict": rep.OnConflict,
+		"total":       rep.Total,
+		"valid":       rep.Valid,
+		"imported":    rep.Imported,
+		"updated":     rep.Updated,
+		"skipped":     rep.Skipped,
+		"errors":      rep.Errors,
+		"report_id":   rep.ID,
+		"message":     rep.message(),
+	}
+}
+
+// Результат записи одной строки
+const (
+	importInserted = "inserted"
+	importUpdated  = "updated"
+	importSkipped  = "skipped"
+)
+
+// importWriter — построчная запись с транзакциями по пакетам. В режиме
+// allOrNothing пакет один на весь файл; после первой ошибки запись
+// прекращается, но строки продолжают проверяться ради полного отчёта.
+type importWriter struct {
+	r       *http.Request
+	opts    importOptions
+	rep     *importReport
+	tx      *sql.Tx
+	seen    map[string]int // ключ → строка, где он встретился первым
+	aborted bool
+
+	// Текущий пакет: счётчики попадают в отчёт только после commit
+	batchFirst, batchRows       int
+	batchInserted, batchUpdated int
+}
+
+// runImport читает источник построчно, проверяет строки и, если это не
+// dry-run, записывает корректные. progress (может быть nil) вызывается
+// после каждой строки. Ошибка возвращается, только если импорт прерван
+// через ctx; отчёт при этом отражает уже закоммиченные пакеты.
+func runImport(ctx context.Context, r *http.Request, src importSource, columns map[string]int,
+	opts importOptions, progress func(rows int)) (*importReport, error) {
+	iw := &importWriter{
+		r:    r,
+		opts: opts,
+		rep: &importReport{
+			ID:         uuid.NewString(),
+			CreatedAt:  time.Now(),
+			Mode:       opts.Mode,
+			OnConflict: opts.OnConflict,
+			DryRun:     opts.DryRun,
+			Errors:     []importRowError{},
+			lang:       requestLang(r),
+		},
+		seen: map[string]int{},
+	}
+	rep := iw.rep
+	defer iw.rollback()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			iw.rollback()
+			rep.Interrupted = true
+			if iw.opts.allOrNothing() && !opts.DryRun {
+				rep.Failed = true
+				rep.Imported, rep.Updated = 0, 0
+			}
+			return rep, err
+		}
+
+		record, rowNum, err := src.Next()
+		if err == io.EOF {
+			break
+		}
+		var row importRow
+		switch {
+		case err != nil:
+			// Битая строка (например, незакрытая кавычка) — фиксируем и продолжаем
+			row = importRow{Row: rowNum}
+			row.fail("", errorLangText(rep.lang, err))
+		case isBlankRow(record):
+			continue
+		default:
+			row = buildImportRow(rep.lang, rowNum, rowValues(record, columns))
+		}
+
+		rep.Total++
+		iw.add(row)
+		if progress != nil {
+			progress(rep.Total)
+		}
+	}
+
+	switch {
+	case opts.DryRun:
+		rep.Skipped = rep.Total - rep.Valid
+	case iw.aborted:
+		iw.rollback()
+		rep.Failed = true
+		rep.Imported, rep.Updated = 0, 0
+		rep.Skipped = rep.Total
+	default:
+		iw.commit()
+	}
+	return rep, nil
+}
+
+// add проверяет ключ строки и записывает её в текущий пакет
+func (iw *importWriter) add(row importRow) {
+	rep := iw.rep
+	iw.checkKey(&row)
+	if len(row.Errors) > 0 {
+		rep.Errors = append(rep.Errors, row.Errors...)
+		rep.Skipped++
+		if iw.opts.allOrNothing() {
+			iw.aborted = true
+		}
+		return
+	}
+	rep.Valid++
+	if iw.opts.allOrNothing() && rep.Valid > cfg.ImportBatchSize && !iw.aborted {
+		rep.Errors = append(rep.Errors, importRowError{Row: row.Row, Error: msg(rep.lang, "import.atomic_limit", cfg.ImportBatchSize)})
+		iw.rollback()
+		iw.aborted = true
+	}
+	if iw.opts.DryRun || iw.aborted {
+		return
+	}
+
+	if iw.tx == nil {
+		tx, err := db.Begin()
+		if err != nil {
+			rep.Errors = append(rep.Errors, importRowError{Row: row.Row, Error: msg(rep.lang, "import.db_error", err)})
+			rep.Skipped++
+			iw.aborted = iw.opts.allOrNothing()
+			return
+		}
+		iw.tx, iw.batchFirst = tx, row.Row
+	}
+
+	action, err := iw.write(row.Item)
+	if err != nil {
+		rep.Errors = append(rep.Errors, importRowError{Row: row.Row, Error: msg(rep.lang, "import.write_error", errorLangText(rep.lang, err))})
+		rep.Skipped++
+		iw.aborted = iw.opts.allOrNothing()
+		return
+	}
+
+	iw.batchRows++
+	switch action {
+	case importInserted:
+		iw.batchInserted++
+	case importUpdated:
+		iw.batchUpdated++
+	case importSkipped:
+		rep.Skipped++
+		rep.Errors = append(rep.Errors, importRowError{Row: row.Row, Field: "key", Error: msg(rep.lang, "import.key_skipped")})
+	}
+
+	if !iw.opts.allOrNothing() && iw.batchRows >= cfg.ImportBatchSize {
+		iw.commit()
+	}
+}
+
+// write записывает строку под точкой сохранения: сбой на середине
+// (например, в аудите) откатывает только эту строку, а не весь пакет
+func (iw *importWriter) write(item *ImportLicense) (string, error) {
+	if _, err := iw.tx.Exec("SAVEPOINT import_row"); err != nil {
+		return "", err
+	}
+	action, err := upsertImported(iw.tx, iw.r, item, iw.opts.OnConflict)
+	if err != nil {
+		iw.tx.Exec("ROLLBACK TO import_row")
+		iw.tx.Exec("RELEASE import_row")
+		return "", err
+	}
+	_, err = iw.tx.Exec("RELEASE import_row")
+	return action, err
+}
+
+// checkKey помечает повтор ключа внутри файла и, при политике fail,
+// ключ, который уже есть в БД. Заодно сверяет current_uses с лимитом, который
+// будет действовать после записи: из файла, у новой лицензии — по умолчанию,
+// у обновляемой — сохранённый
+func (iw *importWriter) checkKey(row *importRow) {
+	if row.Item == nil || row.Item.Key == "" {
+		return
+	}
+	if first, ok := iw.seen[row.Item.Key]; ok {
+		row.fail("key", msg(iw.rep.lang, "import.duplicate_key", first))
+		return
+	}
+	iw.seen[row.Item.Key] = row.Row
+
+	var q queryRower = db
+	if iw.tx != nil {
+		q = iw.tx
+	}
+	item, lang := row.Item, iw.rep.lang
+	var maxUses, currentUses int
+	err := q.QueryRow("SELECT max_uses, current_uses FROM licenses WHERE UPPER(key) = ?", item.Key).
+		Scan(&maxUses, &currentUses)
+	switch {
+	case err != nil && err != sql.ErrNoRows:
+		// Без проверки ключа строку не пишем: конфликт и лимит остались бы непроверенными
+		row.fail("key", msg(lang, "import.db_error", err))
+	case err == sql.ErrNoRows:
+		// Новая лицензия: лимит из файла уже проверен, по умолчанию — здесь
+		if !item.Present["max_uses"] && item.CurrentUses > item.MaxUses {
+			row.fail("current_uses", msg(lang, "import.over_limit", item.MaxUses))
+		}
+	case iw.opts.OnConflict == conflictFail:
+		row.fail("key", msg(lang, "import.key_exists"))
+	case iw.opts.OnConflict == conflictOverwrite:
+		if item.Present["max_uses"] {
+			maxUses = item.MaxUses
+		}
+		if item.Present["current_uses"] {
+			currentUses = item.CurrentUses
+		}
+		switch {
+		case currentUses <= maxUses:
+		case item.Present["current_uses"]:
+			row.fail("current_uses", msg(lang, "import.over_limit", maxUses))
+		default:
+			row.fail("max_uses", msg(lang, "import.below_uses", currentUses))
+		}
+	}
+}
+
+func (iw *importWriter) commit() {
+	if iw.tx == nil {
+		return
+	}
+	rep := iw.rep
+	if err := iw.tx.Commit(); err != nil {
+		rep.Errors = append(rep.Errors, importRowError{
+			Row:   iw.batchFirst,
+			Error: msg(rep.lang, "import.batch_error", iw.batchRows, err),
+		})
+		rep.Skipped += iw.batchRows
+	} else {
+		rep.Imported += iw.batchInserted
+		rep.Updated += iw.batchUpdated
+	}
+	iw.tx = nil
+	iw.batchRows, iw.batchInserted, iw.batchUpdated = 0, 0, 0
+}
+
+func (iw *importWriter) rollback() {
+	if iw.tx == nil {
+		return
+	}
+	iw.tx.Rollback()
+	iw.tx = nil
+	iw.batchRows, iw.batchInserted, iw.batchUpdated = 0, 0, 0
+}
+
+// Колонки, которые overwrite может обновить у существующей лицензии;
+// activated_on — не колонка, а связи с устройствами (license_devices.go)
+var importUpdatableColumns = []string{
+	"description", "expiry_date", "max_uses", "cost", "supplier", "current_uses", "product",
+}
+
+func (item *ImportLicense) columnValue(col string) any {
+	switch col {
+	case "description":
+		return item.Description
+	case "expiry_date":
+		return item.ExpiryDate
+	case "max_uses":
+		return item.MaxUses
+	case "cost":
+		return item.Cost
+	case "supplier":
+		return item.Supplier
+	case "current_uses":
+		return item.CurrentUses
+	case "product":
+		return item.Product
+	}
+	return nil
+}
+
+// upsertImported вставляет лицензию или, если ключ уже есть, поступает по
+// политике onConflict. Каждое изменение сопровождается записью аудита.
+func upsertImported(tx *sql.Tx, r *http.Request, item *ImportLicense, onConflict string) (string, error) {
+	if item.Key != "" {
+		var id int
+		err := tx.QueryRow("SELECT id FROM licenses WHERE UPPER(key) = ?", item.Key).Scan(&id)
+		switch {
+		case err == sql.ErrNoRows:
+			// новый ключ — вставляем ниже
+		case err != nil:
+			return "", err
+		case onConflict == conflictOverwrite:
+			return importUpdated, overwriteImported(tx, r, id, item)
+		case onConflict == conflictFail:
+			return "", newLocalizedError("import.key_exists")
+		default:
+			return importSkipped, nil
+		}
+	}
+
+	key := item.Key
+	if key == "" {
+		key = generateKey()
+	}
+	res, err := tx.Exec(`INSERT INTO licenses
+		(key, description, expiry_date, max_uses, cost, supplier, current_uses, product)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		key, item.Description, item.ExpiryDate, item.MaxUses, item.Cost, item.Supplier,
+		item.CurrentUses, item.Product)
+	if err != nil {
+		return "", err
+	}
+	if item.ActivatedOn != "" {
+		id, err := res.LastInsertId()
+		if err == nil {
+			err = setLicenseDevicesByNames(tx, id, item.ActivatedOn)
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	return importInserted, auditLicenseCreated(tx, r, res, "license.import")
+}
+
+func overwriteImported(tx *sql.Tx, r *http.Request, id int, item *ImportLicense) error {
+	var sets []string
+	var args []any
+	for _, col := range importUpdatableColumns {
+		if item.Present[col] {
+			sets = append(sets, col+" = ?")
+			args = append(args, item.columnValue(col))
+		}
+	}
+	if len(sets) == 0 && !item.Present["activated_on"] {
+		return nil
+	}
+
+	before, err := licenseSnapshot(tx, id)
+	if err != nil {
+		return err
+	}
+	if len(sets) > 0 {
+		args = append(args, id)
+		if _, err := tx.Exec("UPDATE licenses SET "+strings.Join(sets, ", ")+" WHERE id = ?", args...); err != nil {
+			return err
+		}
+	}
+	if item.Present["activated_on"] {
+		if err := setLicenseDevicesByNames(tx, int64(id), item.ActivatedOn); err != nil {
+			return err
+		}
+	}
+	after, err := licenseSnapshot(tx, id)
+	if err != nil {
+		return err
+	}
+	return recordAudit(tx, r, "license.import_update", "license", strconv.Itoa(id), before, after)
+}
+
+// === Хранилище отчётов (в памяти, последние maxImportReports) ===
+const maxImportReports = 50
+
+var importReports = struct {
+	sync.Mutex
+	byID  map[string]*importReport
+	order []string
+}{byID: map[string]*importReport{}}
+
+func saveImportReport(rep *importReport) {
+	importReports.Lock()
+	defer importReports.Unlock()
+	importReports.byID[rep.ID] = rep
+	importReports.order = append(importReports.order, rep.ID)
+	if len(importReports.order) > maxImportReports {
+		delete(importReports.byID, importReports.order[0])
+		importReports.order = importReports.order[1:]
+	}
+}
+
+func getImportReport(id string) *importReport {
+	importReports.Lock()
+	defer importReports.Unlock()
+	return importReports.byID[id]
+}
+
+// GET /api/licenses/import/report/{id}?format=json|csv|xlsx
+func handleImportReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	rep := getImportReport(strings.TrimPrefix(r.URL.Path, "/api/licenses/import/report/"))
+	if rep == nil {
+		httpError(w, r, "import.report_not_found", 404)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", "attachment;filename=import-report.csv")
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"row", "field", "error"})
+		for _, e := range rep.Errors {
+			cw.Write([]string{strconv.Itoa(e.Row), e.Field, e.Error})
+		}
+		cw.Flush()
+
+	case "xlsx":
+		f := excelize.NewFile()
+		sheet := tr(r, "import.report.sheet")
+		f.SetSheetName("Sheet1", sheet)
+		f.SetSheetRow(sheet, "A1", &[]any{tr(r, "import.report.row"), tr(r, "import.report.field"), tr(r, "import.report.error")})
+		for i, e := range rep.Errors {
+			cell, _ := excelize.CoordinatesToCellName(1, i+2)
+			f.SetSheetRow(sheet, cell, &[]any{e.Row, e.Field, e.Error})
+		}
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", "attachment;filename=import-report.xlsx")
+		f.Write(w)
+
+	default:
+		httpError(w, r, "param.choice", 400, "format", "json, csv, xlsx")
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// === СОПОСТАВЛЕНИЕ КОЛОНОК ИМПОРТА ===
+// Колонки файла сопоставляются полям импорта по заголовку (с русскими
+// синонимами) или по явному mapping из запроса: {"Описание": "description",
+// "C": "max_uses", "Примечание": ""} — ключ это текст заголовка или буква
+// колонки, пустое поле означает «не импортировать».
+
+// Синонимы заголовков; сравниваются после normalizeHeader
+var importHeaderAliases = map[string][]string{
+	"description":  {"description", "описание", "наименование", "название", "name"},
+	"expiry_date":  {"expiry_date", "expiry date", "expiry", "expires", "срок действия", "дата окончания", "действует до", "истекает"},
+	"max_uses":     {"max_uses", "max uses", "лимит", "лимит активаций", "макс активаций", "количество мест", "мест"},
+	"key":          {"key", "license key", "ключ", "лицензионный ключ"},
+	"cost":         {"cost", "price", "стоимость", "цена", "сумма"},
+	"supplier":     {"supplier", "vendor", "поставщик", "вендор"},
+	"current_uses": {"current_uses", "current uses", "активаций", "использовано", "текущие активации"},
+	"activated_on": {"activated_on", "activated on", "где активирована", "устройства", "рабочие места"},
+	"product":      {"product", "продукт", "программа", "по"},
+}
+
+var headerAliasIndex = func() map[string]string {
+	idx := map[string]string{}
+	for field, aliases := range importHeaderAliases {
+		for _, a := range aliases {
+			idx[normalizeHeader(a)] = field
+		}
+	}
+	return idx
+}()
+
+var headerCleanup = regexp.MustCompile(`[\s_.:*]+`)
+
+// normalizeHeader: регистр, ё/е, подчёркивания, точки и лишние пробелы не важны
+func normalizeHeader(h string) string {
+	h = strings.ToLower(strings.TrimSpace(h))
+	h = strings.ReplaceAll(h, "ё", "е")
+	return strings.TrimSpace(headerCleanup.ReplaceAllString(h, " "))
+}
+
+var columnLetterPattern = regexp.MustCompile(`^[A-Za-z]{1,3}$`)
+
+// resolveColumns возвращает поле → индекс колонки
+func resolveColumns(header []string, explicit map[string]string) (map[string]int, error) {
+	columns := map[string]int{}
+	ignored := map[int]bool{}
+
+	for ref, field := range explicit {
+		field = strings.TrimSpace(field)
+		if field != "" {
+			if _, ok := importHeaderAliases[field]; !ok {
+				return nil, newLocalizedError("import.mapping_field", field)
+			}
+		}
+
+		idx := -1
+		for i, h := range header {
+			if normalizeHeader(h) == normalizeHeader(ref) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 && columnLetterPattern.MatchString(ref) {
+			if n, err := excelize.ColumnNameToNumber(strings.ToUpper(ref)); err == nil {
+				idx = n - 1
+			}
+		}
+		if idx < 0 {
+			return nil, newLocalizedError("import.mapping_column", ref)
+		}
+
+		if field == "" {
+			ignored[idx] = true
+			continue
+		}
+		columns[field] = idx
+	}
+
+	// Остальное — по заголовкам
+	for i, h := range header {
+		if ignored[i] {
+			continue
+		}
+		field, ok := headerAliasIndex[normalizeHeader(h)]
+		if !ok {
+			continue
+		}
+		if _, taken := columns[field]; !taken {
+			columns[field] = i
+		}
+	}
+	return columns, nil
+}
+
+func missingRequired(columns map[string]int) []string {
+	missing := []string{}
+	for _, col := range importRequiredColumns {
+		if _, ok := columns[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	return missing
+}
+
+// rowValues собирает значения строки по сопоставлению колонок
+func rowValues(record []string, columns map[string]int) map[string]string {
+	values := map[string]string{}
+	for field, i := range columns {
+		if i < len(record) {
+			values[field] = record[i]
+		}
+	}
+	return values
+}
+
+// === Даты ===
+var importDateLayouts = []string{
+	"2006-01-02",
+	"02.01.2006",
+	"2.1.2006",
+	"2006/01/02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"02.01.2006 15:04:05",
+}
+
+// normalizeImportDate приводит дату к ГГГГ-ММ-ДД; понимает и серийные
+// номера Excel (дни с 1899-12-30), если ячейка не отформатирована как дата
+func normalizeImportDate(v string) (string, error) {
+	v = strings.TrimSpace(v)
+	for _, layout := range importDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	if serial, err := strconv.ParseFloat(v, 64); err == nil && serial > 0 && serial < 2958466 {
+		t, err := excelize.ExcelDateToTime(math.Floor(serial), false)
+		if err == nil {
+			return t.Format("2006-01-02"), nil
+		}
+	}
+	return "", newLocalizedError("import.date_invalid", v)
+}
+
+// === Параметры разбора из формы ===
+type importParseOptions struct {
+	Sheet   string            // лист XLSX; пусто — первый
+	Mapping map[string]string // явное сопоставление колонок
+}
+
+func parseImportOptions(r *http.Request) (importParseOptions, error) {
+	opts := importParseOptions{Sheet: strings.TrimSpace(r.FormValue("sheet"))}
+	if m := strings.TrimSpace(r.FormValue("mapping")); m != "" {
+		if err := json.Unmarshal([]byte(m), &opts.Mapping); err != nil {
+			return opts, fmt.Errorf("mapping: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// === ПРЕДПРОСМОТР СОПОСТАВЛЕНИЯ ===
+// POST /api/licenses/import/preview (multipart: file, format, sheet, mapping)
+// Показывает листы, заголовки, найденное сопоставление и первые строки,
+// чтобы пользователь поправил mapping до настоящего импорта.
+func handleImportPreview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, cfg.ImportMaxBytes)
+	path, _, err := saveUpload(r, "file")
+	if err != nil {
+		httpError(w, r, "err.file_required", 400)
+		return
+	}
+	defer os.Remove(path)
+
+	format := r.FormValue("format")
+	if format != "csv" && format != "xlsx" {
+		httpError(w, r, "param.choice", 400, "format", "csv, xlsx")
+		return
+	}
+	opts, err := parseImportOptions(r)
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+
+	src, err := openImportSource(path, format, opts.Sheet)
+	if err != nil {
+		httpError(w, r, "import.parse_failed", 400, errorText(r, err))
+		return
+	}
+	defer src.Close()
+
+	sheets, sheet := []string{}, ""
+	if x, ok := src.(*xlsxSource); ok {
+		sheets, sheet = x.sheets, x.sheet
+	}
+	header := src.Header()
+	columns, err := resolveColumns(header, opts.Mapping)
+	if err != nil {
+		http.Error(w, errorText(r, err), 400)
+		return
+	}
+
+	mapping := map[string]string{}
+	for field, i := range columns {
+		if i < len(header) {
+			mapping[header[i]] = field
+		}
+	}
+	unmapped := []string{}
+	for _, h := range header {
+		if _, ok := mapping[h]; !ok && strings.TrimSpace(h) != "" {
+			unmapped = append(unmapped, h)
+		}
+	}
+
+	sample := []map[string]string{}
+	for len(sample) < 5 {
+		record, _, err := src.Next()
+		if err != nil {
+			break
+		}
+		if !isBlankRow(record) {
+			sample = append(sample, rowValues(record, columns))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"sheets":           sheets,
+		"sheet":            sheet,
+		"headers":          header,
+		"mapping":          mapping,
+		"unmapped":         unmapped,
+		"missing_required": missingRequired(columns),
+		"fields":           importColumns,
+		"sample":           sample,
+	})
+}
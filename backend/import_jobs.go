@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// === ФОНОВЫЙ ИМПОРТ ===
+// POST /api/licenses/import с async=1 возвращает job_id, сам импорт идёт
+// в фоне (goBackground), а ход виден по GET /api/licenses/import/jobs/{id}.
+// Для CSV прогресс считается по прочитанным байтам, для XLSX известно
+// только число обработанных строк.
+
+const (
+	importJobQueued  = "queued"
+	importJobRunning = "running"
+	importJobDone    = "done"
+	importJobFailed  = "failed"
+)
+
+type importJobStatus struct {
+	ID         string         `json:"id"`
+	Status     string         `json:"status"`
+	Format     string         `json:"format"`
+	CreatedAt  time.Time      `json:"created_at"`
+	StartedAt  *time.Time     `json:"started_at,omitempty"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+	Rows       int            `json:"rows"` // обработано строк
+	BytesRead  int64          `json:"bytes_read,omitempty"`
+	BytesTotal int64          `json:"bytes_total"`
+	Percent    *float64       `json:"percent,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	ReportID   string         `json:"report_id,omitempty"`
+	Result     map[string]any `json:"result,omitempty"`
+}
+
+type importJob struct {
+	id string
+	mu sync.Mutex
+	st importJobStatus
+}
+
+func newImportJob(format string, size int64) *importJob {
+	job := &importJob{id: uuid.NewString()}
+	job.st = importJobStatus{
+		ID:         job.id,
+		Status:     importJobQueued,
+		Format:     format,
+		CreatedAt:  time.Now(),
+		BytesTotal: size,
+	}
+	saveImportJob(job)
+	return job
+}
+
+func (job *importJob) status() importJobStatus {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.st
+}
+
+func (job *importJob) update(fn func(st *importJobStatus)) {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	fn(&job.st)
+}
+
+func (job *importJob) run(ctx context.Context, r *http.Request, src importSource, columns map[string]int, opts importOptions) {
+	now := time.Now()
+	job.update(func(st *importJobStatus) {
+		st.Status = importJobRunning
+		st.StartedAt = &now
+	})
+
+	report, err := runImport(ctx, r, src, columns, opts, func(rows int) {
+		read, total := src.Progress()
+		job.update(func(st *importJobStatus) {
+			st.Rows = rows
+			if total > 0 {
+				st.BytesRead = read
+				pct := float64(read) * 100 / float64(total)
+				st.Percent = &pct
+			}
+		})
+	})
+	saveImportReport(report)
+
+	finished := time.Now()
+	job.update(func(st *importJobStatus) {
+		st.FinishedAt = &finished
+		st.Rows = report.Total
+		st.ReportID = report.ID
+		st.Result = report.result()
+		switch {
+		case err != nil:
+			st.Status = importJobFailed
+			st.Error = msg(report.lang, "import.interrupted", err)
+		case report.Failed:
+			st.Status = importJobFailed
+			st.Error = report.message()
+		default:
+			st.Status = importJobDone
+			if st.Percent != nil {
+				full := 100.0
+				st.Percent = &full
+			}
+		}
+	})
+	if err != nil {
+		log.Printf("Фоновый импорт %s прерван: %v", job.id, err)
+	}
+}
+
+// === Хранилище задач (в памяти, последние maxImportJobs) ===
+const maxImportJobs = 50
+
+var importJobs = struct {
+	sync.Mutex
+	byID  map[string]*importJob
+	order []string
+}{byID: map[string]*importJob{}}
+
+func saveImportJob(job *importJob) {
+	importJobs.Lock()
+	defer importJobs.Unlock()
+	importJobs.byID[job.id] = job
+	importJobs.order = append(importJobs.order, job.id)
+	if len(importJobs.order) > maxImportJobs {
+		delete(importJobs.byID, importJobs.order[0])
+		importJobs.order = importJobs.order[1:]
+	}
+}
+
+func getImportJob(id string) *importJob {
+	importJobs.Lock()
+	defer importJobs.Unlock()
+	return importJobs.byID[id]
+}
+
+// GET /api/licenses/import/jobs/{id}
+func handleImportJob(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	job := getImportJob(strings.TrimPrefix(r.URL.Path, "/api/licenses/import/jobs/"))
+	if job == nil {
+		httpError(w, r, "import.job_not_found", 404)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.status())
+}
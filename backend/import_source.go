@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// === ПОСТРОЧНОЕ ЧТЕНИЕ ФАЙЛОВ ИМПОРТА ===
+// Загрузка сохраняется во временный файл, дальше CSV читается потоком, а
+// XLSX — итератором строк excelize, так что файл целиком в память не попадает.
+
+type importSource interface {
+	Header() []string
+	// Next возвращает следующую запись и её номер строки в файле; io.EOF в конце
+	Next() ([]string, int, error)
+	// Progress — прочитано байт из total (0, если оценить нельзя)
+	Progress() (read, total int64)
+	Close() error
+}
+
+// saveUpload копирует файл из multipart-формы во временный файл
+func saveUpload(r *http.Request, field string) (path string, size int64, err error) {
+	file, _, err := r.FormFile(field)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	tmp, err := os.CreateTemp("", "license-import-*")
+	if err != nil {
+		return "", 0, err
+	}
+	size, err = io.Copy(tmp, file)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", 0, err
+	}
+	return tmp.Name(), size, nil
+}
+
+// openImportSource открывает файл и читает строку заголовков
+func openImportSource(path, format, sheet string) (importSource, error) {
+	if format == "csv" {
+		return openCSVSource(path)
+	}
+	return openXLSXSource(path, sheet)
+}
+
+// === CSV ===
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+type csvSource struct {
+	f      *os.File
+	cr     *countingReader
+	size   int64
+	reader *csv.Reader
+	header []string
+	row    int
+}
+
+func openCSVSource(path string) (*csvSource, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	src := &csvSource{f: f, cr: &countingReader{r: f}, size: st.Size()}
+	br := bufio.NewReader(src.cr)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) { // BOM из Excel
+		br.Discard(3)
+	}
+	src.reader = csv.NewReader(br)
+	src.reader.Comma = detectCSVDelimiter(br)
+	src.reader.FieldsPerRecord = -1
+	src.reader.ReuseRecord = false
+
+	src.header, err = src.reader.Read()
+	if err == io.EOF {
+		f.Close()
+		return nil, newLocalizedError("import.empty_file")
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	src.row = 1
+	return src, nil
+}
+
+// detectCSVDelimiter выбирает разделитель по строке заголовков: экспорт для
+// русского Excel (delimiter=semicolon) пишет через «;», копии из таблиц — через
+// табуляцию
+func detectCSVDelimiter(br *bufio.Reader) rune {
+	head, _ := br.Peek(br.Size())
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	best, bestN := ',', bytes.Count(head, []byte{','})
+	for _, d := range []rune{';', '\t'} {
+		if n := bytes.Count(head, []byte(string(d))); n > bestN {
+			best, bestN = d, n
+		}
+	}
+	return best
+}
+
+func (s *csvSource) Header() []string { return s.header }
+
+func (s *csvSource) Next() ([]string, int, error) {
+	record, err := s.reader.Read()
+	if err == io.EOF {
+		return nil, 0, io.EOF
+	}
+	s.row++
+	if err != nil {
+		// Номер строки берём у парсера: запись может занимать несколько строк
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			s.row = perr.StartLine
+		}
+	}
+	return record, s.row, err
+}
+
+func (s *csvSource) Progress() (int64, int64) { return s.cr.n.Load(), s.size }
+
+func (s *csvSource) Close() error { return s.f.Close() }
+
+// === XLSX ===
+type xlsxSource struct {
+	f      *excelize.File
+	rows   *excelize.Rows
+	sheets []string
+	sheet  string
+	header []string
+	row    int
+}
+
+func openXLSXSource(path, sheet string) (*xlsxSource, error) {
+	f, err := excelize.OpenFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	src := &xlsxSource{f: f, sheets: f.GetSheetList(), sheet: f.GetSheetName(0)}
+	if sheet != "" {
+		src.sheet = ""
+		for _, s := range src.sheets {
+			if strings.EqualFold(s, sheet) {
+				src.sheet = s
+			}
+		}
+		if src.sheet == "" {
+			f.Close()
+			return nil, newLocalizedError("import.sheet_not_found", sheet)
+		}
+	}
+
+	src.rows, err = f.Rows(src.sheet)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	header, _, err := src.Next()
+	if err == io.EOF {
+		src.Close()
+		return nil, newLocalizedError("import.empty_sheet")
+	}
+	if err != nil {
+		src.Close()
+		return nil, err
+	}
+	src.header = header
+	return src, nil
+}
+
+func (s *xlsxSource) Header() []string { return s.header }
+
+// Next отдаёт значения без числовых форматов: даты — серийными номерами Excel
+func (s *xlsxSource) Next() ([]string, int, error) {
+	if !s.rows.Next() {
+		if err := s.rows.Error(); err != nil {
+			return nil, 0, err
+		}
+		return nil, 0, io.EOF
+	}
+	s.row++
+	cols, err := s.rows.Columns(excelize.Options{RawCellValue: true})
+	return cols, s.row, err
+}
+
+func (s *xlsxSource) Progress() (int64, int64) { return 0, 0 }
+
+func (s *xlsxSource) Close() error {
+	if s.rows != nil {
+		s.rows.Close()
+	}
+	return s.f.Close()
+}
+
+// importSourceColumns сопоставляет колонки источника полям импорта.
+// Для XLSX без узнаваемых заголовков действует старый порядок importColumns.
+func importSourceColumns(src importSource, mapping map[string]string) (map[string]int, error) {
+	columns, err := resolveColumns(src.Header(), mapping)
+	if err != nil {
+		return nil, err
+	}
+	if _, isXLSX := src.(*xlsxSource); isXLSX && len(columns) == 0 && len(mapping) == 0 {
+		for i, col := range importColumns {
+			columns[col] = i
+		}
+	}
+	if missing := missingRequired(columns); len(missing) > 0 {
+		return nil, newLocalizedError("import.missing_columns", strings.Join(missing, ", "))
+	}
+	return columns, nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// === СВЯЗИ ЛИЦЕНЗИЙ С УСТРОЙСТВАМИ ===
+// license_devices — многие-ко-многим между лицензиями и устройствами рабочих
+// мест. licenses.activated_on больше не вводится вручную: это имена (или MAC)
+// связанных устройств через запятую, их пересчитывает syncActivatedOn при
+// каждом изменении связей или устройств. Текст из старых баз, импорта и
+// дампов превращается в связи (setLicenseDevicesByNames): устройство ищется
+// по имени или MAC, а если не найдено — создаётся вне кабинетов.
+//
+// GET    /api/licenses/{id}/devices             — связанные устройства
+// POST   /api/devices/{id}/licenses             — {"license_id"} или {"key"} → устройство
+// DELETE /api/devices/{id}/licenses/{licenseId} — → устройство
+//
+// Через API к лицензии можно привязать не больше max_uses устройств.
+
+// labelMAC — MAC в виде, как он хранится в devices; "" — если label не MAC
+func labelMAC(label string) string {
+	mac, err := normalizeMAC(label)
+	if err != nil {
+		return ""
+	}
+	return mac
+}
+
+// syncActivatedOn пересчитывает licenses.activated_on по связям; без ids —
+// у всех лицензий, где есть связи или непустой текст
+func syncActivatedOn(tx *sql.Tx, ids ...int64) error {
+	where := "id IN (SELECT license_id FROM license_devices) OR COALESCE(activated_on, '') <> ''"
+	args := make([]any, len(ids))
+	if len(ids) > 0 {
+		for i, id := range ids {
+			args[i] = id
+		}
+		where = "id IN (" + strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ") + ")"
+	}
+	_, err := tx.Exec(`UPDATE licenses SET activated_on = COALESCE((
+			SELECT group_concat(CASE WHEN d.name <> '' THEN d.name ELSE d.mac END, ', ')
+			FROM license_devices ld JOIN devices d ON d.id = ld.device_id
+			WHERE ld.license_id = licenses.id), '')
+		WHERE `+where, args...)
+	return err
+}
+
+// pruneLicenseDevices удаляет связи с удалёнными устройствами и лицензиями;
+// activated_on после этого пересчитывает вызывающий
+func pruneLicenseDevices(tx *sql.Tx) error {
+	_, err := tx.Exec(`DELETE FROM license_devices
+		WHERE device_id NOT IN (SELECT id FROM devices) OR license_id NOT IN (SELECT id FROM licenses)`)
+	return err
+}
+
+// deviceLicenseIDs — лицензии, связанные с устройством
+func deviceLicenseIDs(q workplacesQuerier, deviceID int64) ([]int64, error) {
+	rows, err := q.Query("SELECT license_id FROM license_devices WHERE device_id = ? ORDER BY license_id", deviceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func linkDevice(tx *sql.Tx, licenseID, deviceID int64) error {
+	_, err := tx.Exec("INSERT OR IGNORE INTO license_devices (license_id, device_id, linked_at) VALUES (?, ?, ?)",
+		licenseID, deviceID, time.Now().UTC().Format(dumpTimeFormat))
+	return err
+}
+
+// findDeviceByLabel — устройство с таким именем (без учёта регистра) или MAC; 0 — нет
+func findDeviceByLabel(q queryRower, label string) (int64, error) {
+	var id int64
+	err := q.QueryRow(`SELECT id FROM devices
+		WHERE (name <> '' AND ulower(name) = ulower(?)) OR (mac <> '' AND mac = ?)
+		ORDER BY id LIMIT 1`, label, labelMAC(label)).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return id, err
+}
+
+// setLicenseDevicesByNames заменяет связи лицензии устройствами из списка
+// через запятую, создавая недостающие вне кабинетов
+func setLicenseDevicesByNames(tx *sql.Tx, licenseID int64, list string) error {
+	if _, err := tx.Exec("DELETE FROM license_devices WHERE license_id = ?", licenseID); err != nil {
+		return err
+	}
+	created := false
+	seen := map[string]bool{}
+	for _, label := range strings.Split(list, ",") {
+		label = strings.TrimSpace(label)
+		if label == "" || seen[strings.ToLower(label)] {
+			continue
+		}
+		seen[strings.ToLower(label)] = true
+
+		deviceID, err := findDeviceByLabel(tx, label)
+		if err != nil {
+			return err
+		}
+		if deviceID == 0 {
+			d := workplaceDevice{Name: label, Type: "desktop", X: 100, Y: 100}
+			if mac := labelMAC(label); mac != "" {
+				d.Name, d.MAC = "", mac
+			}
+			if err := insertDevice(tx, &d); err != nil {
+				return err
+			}
+			deviceID, created = d.ID, true
+		}
+		if err := linkDevice(tx, licenseID, deviceID); err != nil {
+			return err
+		}
+	}
+	if created {
+		if err := bumpWorkplacesRevision(tx); err != nil {
+			return err
+		}
+	}
+	return syncActivatedOn(tx, licenseID)
+}
+
+// migrateLicenseDevices превращает текст activated_on в связи у лицензий,
+// у которых связей ещё нет (старые базы и дампы)
+func migrateLicenseDevices(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, activated_on FROM licenses
+		WHERE COALESCE(activated_on, '') <> '' AND id NOT IN (SELECT license_id FROM license_devices)`)
+	if err != nil {
+		return err
+	}
+	type pending struct {
+		id   int64
+		list string
+	}
+	var todo []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.list); err != nil {
+			rows.Close()
+			return err
+		}
+		todo = append(todo, p)
+	}
+	rows.Close()
+	for _, p := range todo {
+		if err := setLicenseDevicesByNames(tx, p.id, p.list); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// refreshLicenseDevices приводит связи в порядок после запуска и
+// восстановления дампа: убирает висячие, строит недостающие из текста и
+// пересчитывает activated_on у всех лицензий
+func refreshLicenseDevices(tx *sql.Tx) error {
+	if err := pruneLicenseDevices(tx); err != nil {
+		return err
+	}
+	if err := migrateLicenseDevices(tx); err != nil {
+		return err
+	}
+	return syncActivatedOn(tx)
+}
+
+// activateOnDevice связывает лицензию с устройством, переданным при проверке
+// ключа, если такое устройство есть на схеме рабочих мест
+func activateOnDevice(tx *sql.Tx, licenseID int64, device string) error {
+	if device == "" {
+		return nil
+	}
+	deviceID, err := findDeviceByLabel(tx, device)
+	if err != nil || deviceID == 0 {
+		return err
+	}
+	if err := linkDevice(tx, licenseID, deviceID); err != nil {
+		return err
+	}
+	return syncActivatedOn(tx, licenseID)
+}
+
+// === API ===
+func handleLicenseDevices(w http.ResponseWriter, r *http.Request, licenseID string) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	l, err := licenseSnapshot(db, licenseID)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if l == nil {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+
+	rows, err := db.Query("SELECT "+deviceColumns+` FROM devices
+		WHERE id IN (SELECT device_id FROM license_devices WHERE license_id = ?) ORDER BY id`, l.ID)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	devices := []workplaceDevice{}
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			rows.Close()
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		devices = append(devices, *d)
+	}
+	rows.Close()
+	for i := range devices {
+		if devices[i].LicenseIDs, err = deviceLicenseIDs(db, devices[i].ID); err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+	}
+	writeEntity(w, 200, devices)
+}
+
+// handleDeviceLicenses — /api/devices/{id}/licenses[/{licenseId}]
+func handleDeviceLicenses(w http.ResponseWriter, r *http.Request, deviceID int64, rest []string) {
+	var licenseID int64
+	switch {
+	case len(rest) == 0 && r.Method == "POST":
+		var in struct {
+			LicenseID int64  `json:"license_id"`
+			Key       string `json:"key"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		licenseID = in.LicenseID
+		if key := strings.ToUpper(strings.TrimSpace(in.Key)); key != "" {
+			if err := db.QueryRow("SELECT id FROM licenses WHERE UPPER(key) = ?", key).Scan(&licenseID); err == sql.ErrNoRows {
+				httpError(w, r, "validate.not_found", 404)
+				return
+			} else if err != nil {
+				httpError(w, r, "err.db", 500)
+				return
+			}
+		}
+	case len(rest) == 1 && r.Method == "DELETE":
+		id, err := strconv.ParseInt(rest[0], 10, 64)
+		if err != nil {
+			httpError(w, r, "param.invalid", 400, "licenseId")
+			return
+		}
+		licenseID = id
+	case len(rest) > 1:
+		httpError(w, r, "err.not_found", 404)
+		return
+	default:
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	device, err := deviceByID(tx, deviceID)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	l, err := licenseSnapshot(tx, licenseID)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if device == nil || l == nil {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+	link := map[string]any{"license_id": l.ID, "license_key": l.Key}
+	entityID := strconv.FormatInt(deviceID, 10)
+
+	if r.Method == "POST" {
+		var linked, exists int
+		tx.QueryRow("SELECT COUNT(*) FROM license_devices WHERE license_id = ?", l.ID).Scan(&linked)
+		tx.QueryRow("SELECT COUNT(*) FROM license_devices WHERE license_id = ? AND device_id = ?", l.ID, deviceID).Scan(&exists)
+		if exists == 0 && linked >= l.MaxUses {
+			httpError(w, r, "license_devices.limit", http.StatusConflict, linked, l.MaxUses)
+			return
+		}
+		err = linkDevice(tx, int64(l.ID), deviceID)
+		if err == nil {
+			err = recordAudit(tx, r, "device.link", "device", entityID, nil, link)
+		}
+	} else {
+		_, err = tx.Exec("DELETE FROM license_devices WHERE license_id = ? AND device_id = ?", l.ID, deviceID)
+		if err == nil {
+			err = recordAudit(tx, r, "device.unlink", "device", entityID, link, nil)
+		}
+	}
+	if err == nil {
+		err = syncActivatedOn(tx, int64(l.ID))
+	}
+	if err == nil {
+		device, err = deviceByID(tx, deviceID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.save", 500)
+		return
+	}
+	writeEntity(w, 200, device)
+}
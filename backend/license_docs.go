@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// === ДОКУМЕНТЫ ПО КОНКРЕТНОЙ ЛИЦЕНЗИИ ===
+// GET /api/licenses/{id}/docs/{invoice|certificate|eula}[?format=pdf]
+// Шаблоны — документы license_*_text на языке запроса (см. documents.go), в них доступен {{.License}},
+// {{.Number}}, {{.IssuedAt}}, {{.LicenseDevices}} и {{.SeatPrice}}.
+// Номер выдаётся при первом формировании документа и дальше не меняется.
+
+// Тип документа → ключ шаблона (заголовок — docTitle) и префикс номера;
+// номер один на все языки документа
+var licenseDocTypes = map[string]struct{ key, prefix string }{
+	"invoice":     {"license_invoice_text", "ТН"},
+	"certificate": {"license_certificate_text", "СЕРТ"},
+	"eula":        {"license_eula_text", "EULA"},
+}
+
+// issueLicenseDoc возвращает номер документа, при первом обращении — следующий
+// по порядку для этого типа. Запись документа попадает в журнал аудита.
+func issueLicenseDoc(r *http.Request, docType string, licenseID int) (string, time.Time, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	var number, issuedAt string
+	err = tx.QueryRow("SELECT number, issued_at FROM license_documents WHERE type = ? AND license_id = ?",
+		docType, licenseID).Scan(&number, &issuedAt)
+	if err == nil {
+		t, _ := time.ParseInLocation(dumpTimeFormat, issuedAt, time.Local)
+		return number, t, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", time.Time{}, err
+	}
+
+	var seq int
+	if err := tx.QueryRow("SELECT COALESCE(MAX(seq), 0) + 1 FROM license_documents WHERE type = ?", docType).Scan(&seq); err != nil {
+		return "", time.Time{}, err
+	}
+	now := time.Now()
+	number = fmt.Sprintf("%s-%06d", licenseDocTypes[docType].prefix, seq)
+	_, err = tx.Exec("INSERT INTO license_documents (type, license_id, seq, number, issued_at) VALUES (?, ?, ?, ?, ?)",
+		docType, licenseID, seq, number, now.Format(dumpTimeFormat))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	after := map[string]any{"type": docType, "license_id": licenseID, "number": number}
+	if err := recordAudit(tx, r, "document.issue", "license", fmt.Sprint(licenseID), nil, after); err != nil {
+		return "", time.Time{}, err
+	}
+	return number, now, tx.Commit()
+}
+
+func handleLicenseDoc(w http.ResponseWriter, r *http.Request, id, docType string) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	kind, ok := licenseDocTypes[docType]
+	if !ok {
+		httpError(w, r, "doc.unknown_type", 404)
+		return
+	}
+	pdf, ok := wantPDF(w, r)
+	if !ok {
+		return
+	}
+
+	doc, err := currentDocVersion(db, kind.key, requestLang(r))
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if doc == nil {
+		httpError(w, r, "doc.not_published", 404)
+		return
+	}
+
+	l, err := licenseSnapshot(db, id)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if l == nil {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+	l.ExpiryDate = exportDate(l.ExpiryDate)
+
+	number, issuedAt, err := issueLicenseDoc(r, docType, l.ID)
+	if err != nil {
+		log.Printf("Ошибка выдачи номера документа %s для лицензии %d: %v", docType, l.ID, err)
+		httpError(w, r, "err.db", 500)
+		return
+	}
+
+	ctx := loadDocContext(doc.Locale)
+	ctx.Document = doc
+	ctx.License = l
+	ctx.Number = number
+	ctx.IssuedAt = issuedAt
+	writeDoc(w, r, kind.key, ctx, pdf, fmt.Sprintf("%s-%d", docType, l.ID))
+}
+
+// LicenseDevices — устройства, на которых активирована лицензия документа:
+// связанные на схеме рабочих мест, затем имена из журнала активаций
+func (c *docContext) LicenseDevices() []string {
+	if c.License == nil {
+		return nil
+	}
+	seen := map[string]bool{}
+	var devices []string
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[strings.ToLower(name)] {
+			seen[strings.ToLower(name)] = true
+			devices = append(devices, name)
+		}
+	}
+	rows, err := db.Query(`SELECT CASE WHEN d.name <> '' THEN d.name ELSE d.mac END
+		FROM license_devices ld JOIN devices d ON d.id = ld.device_id
+		WHERE ld.license_id = ? ORDER BY ld.linked_at, d.id`, c.License.ID)
+	if err != nil {
+		return devices
+	}
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil {
+			add(name)
+		}
+	}
+	rows.Close()
+
+	rows, err = db.Query(`SELECT DISTINCT device_name FROM activation_log
+		WHERE license_key = ? AND COALESCE(device_name, '') != '' ORDER BY activated_at`, c.License.Key)
+	if err != nil {
+		return devices
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if rows.Scan(&name) == nil {
+			add(name)
+		}
+	}
+	return devices
+}
+
+// SeatPrice — цена одного рабочего места: стоимость лицензии / число активаций
+func (c *docContext) SeatPrice() float64 {
+	if c.License == nil || c.License.MaxUses <= 0 {
+		return 0
+	}
+	return c.License.Cost / float64(c.License.MaxUses)
+}
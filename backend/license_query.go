@@ -0,0 +1,116 @@
+package main
+
+import (
+	"database/sql"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Встроенный LOWER в SQLite понимает только латиницу; для поиска по
+// кириллице драйвер регистрирует ulower на каждом соединении
+const sqliteDriver = "sqlite3_lm"
+
+func init() {
+	sql.Register(sqliteDriver, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.RegisterFunc("ulower", strings.ToLower, true)
+		},
+	})
+}
+
+// === ФИЛЬТРЫ СПИСКА ЛИЦЕНЗИЙ ===
+// Общие для GET /api/licenses и /api/licenses/export:
+//
+//	q=текст                    — поиск по ключу, описанию, поставщику и продукту
+//	status=active|expired|exhausted|expiring — expiring: истекает в ближайшие 7 дней
+//	supplier=..., product=...  — точное совпадение
+//	expiry_from=, expiry_to=   — срок действия в диапазоне (ГГГГ-ММ-ДД)
+//	sort=created_at|expiry_date|description|cost|current_uses, order=asc|desc
+
+type licenseFilter struct {
+	where   []string
+	args    []any
+	orderBy string
+}
+
+var licenseSortColumns = map[string]bool{
+	"created_at": true, "expiry_date": true, "description": true, "cost": true, "current_uses": true,
+}
+
+func parseLicenseFilter(q url.Values) (licenseFilter, error) {
+	f := licenseFilter{where: []string{"1=1"}, orderBy: "created_at DESC"}
+	today := time.Now().Format("2006-01-02")
+
+	if v := strings.TrimSpace(q.Get("q")); v != "" {
+		like := "%" + strings.ToLower(v) + "%"
+		f.where = append(f.where, `(ulower(key) LIKE ? OR ulower(description) LIKE ?
+			OR ulower(COALESCE(supplier, '')) LIKE ? OR ulower(COALESCE(product, '')) LIKE ?)`)
+		f.args = append(f.args, like, like, like, like)
+	}
+
+	switch q.Get("status") {
+	case "":
+	case "active":
+		f.where = append(f.where, "expiry_date >= ? AND current_uses < max_uses")
+		f.args = append(f.args, today)
+	case "expired":
+		f.where = append(f.where, "expiry_date < ?")
+		f.args = append(f.args, today)
+	case "exhausted":
+		f.where = append(f.where, "current_uses >= max_uses")
+	case "expiring":
+		f.where = append(f.where, "expiry_date >= ? AND expiry_date <= ?")
+		f.args = append(f.args, today, time.Now().Add(7*24*time.Hour).Format("2006-01-02"))
+	default:
+		return f, newLocalizedError("param.choice", "status", "active, expired, exhausted, expiring")
+	}
+
+	for _, col := range []string{"supplier", "product"} {
+		if v := strings.TrimSpace(q.Get(col)); v != "" {
+			f.where = append(f.where, "COALESCE("+col+", '') = ?")
+			f.args = append(f.args, v)
+		}
+	}
+
+	for _, b := range []struct{ param, op string }{{"expiry_from", ">="}, {"expiry_to", "<="}} {
+		v := q.Get(b.param)
+		if v == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return f, newLocalizedError("param.date", b.param)
+		}
+		f.where = append(f.where, "expiry_date "+b.op+" ?")
+		f.args = append(f.args, v)
+	}
+
+	if s := q.Get("sort"); s != "" {
+		if !licenseSortColumns[s] {
+			return f, newLocalizedError("filter.sort_column", s)
+		}
+		order := "DESC"
+		if strings.EqualFold(q.Get("order"), "asc") {
+			order = "ASC"
+		}
+		f.orderBy = s + " " + order
+	}
+	return f, nil
+}
+
+// queryLicenses выполняет выборку по фильтру; строки читаются scanLicense
+func queryLicenses(f licenseFilter) (*sql.Rows, error) {
+	return db.Query(`SELECT
+			id, key, description, expiry_date, max_uses, current_uses, created_at,
+			COALESCE(cost, 0), COALESCE(supplier, ''), COALESCE(activated_on, ''), COALESCE(product, '')
+		FROM licenses WHERE `+strings.Join(f.where, " AND ")+` ORDER BY `+f.orderBy, f.args...)
+}
+
+func scanLicense(rows *sql.Rows) (License, error) {
+	var l License
+	err := rows.Scan(&l.ID, &l.Key, &l.Description, &l.ExpiryDate, &l.MaxUses, &l.CurrentUses, &l.CreatedAt,
+		&l.Cost, &l.Supplier, &l.ActivatedOn, &l.Product)
+	return l, err
+}
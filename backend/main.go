@@ -1,20 +1,17 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/gocarina/gocsv"
 	"github.com/google/uuid"
-	"github.com/xuri/excelize/v2" 
 	_ "github.com/mattn/go-sqlite3"
 )
 
@@ -30,18 +27,23 @@ type License struct {
     // ←←← НОВЫЕ ПОЛЯ
     Cost         float64   `json:"cost,omitempty"`
     Supplier     string    `json:"supplier,omitempty"`
-    ActivatedOn  string    `json:"activated_on,omitempty"`  // например: "PC-IVANOV, НОУТ-БУХ, Сервер-01"
+    ActivatedOn  string    `json:"activated_on,omitempty"`  // из license_devices: "PC-IVANOV, НОУТ-БУХ, Сервер-01"
+    Product      string    `json:"product,omitempty"`
 }
 
 var db *sql.DB
 
 func main() {
+	cfg = loadConfig()
+	if err := cfg.validate(); err != nil {
+		log.Fatal("Ошибка конфигурации:", err)
+	}
+
 	var err error
-	db, err = sql.Open("sqlite3", "./licenses.db")
+	db, err = sql.Open(sqliteDriver, cfg.DBPath+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 
 	// === Создание таблиц ===
 	_, err = db.Exec(`
@@ -55,7 +57,8 @@ func main() {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			cost REAL DEFAULT 0,
 			supplier TEXT,
-			activated_on TEXT
+			activated_on TEXT,
+			product TEXT
 		);
 	`)
 	if err != nil {
@@ -85,385 +88,267 @@ func main() {
 		log.Fatal("Ошибка создания settings:", err)
 	}
 
-	// === Добавляем недостающие колонки (если вдруг старый БД) ===
-	db.Exec("ALTER TABLE licenses ADD COLUMN cost REAL DEFAULT 0")
-	db.Exec("ALTER TABLE licenses ADD COLUMN supplier TEXT")
-	db.Exec("ALTER TABLE licenses ADD COLUMN activated_on TEXT")
-
-	// === Заполняем настройки компании ===
+	// Служебные значения (версии сидов и миграций) — отдельно от settings,
+	// чтобы их не перетирал POST /api/settings
 	_, err = db.Exec(`
-		INSERT OR REPLACE INTO settings (key, value) VALUES
-		('company_name', 'LicenseCore Inc.'),
-		('legal_name', 'ООО «ЛицензКор»'),
-		('inn', '7712345678'),
-		('ogrn', '1234567890123'),
-		('legal_address', 'г. Москва, ул. Примерная, д. 10, офис 501'),
-		('support_email', 'support@licensecore.app'),
-		('website', 'https://licensecore.app')
+		CREATE TABLE IF NOT EXISTS meta (
+			key TEXT PRIMARY KEY,
+			value TEXT
+		);
 	`)
 	if err != nil {
-		log.Println("Ошибка базовых настроек:", err)
+		log.Fatal("Ошибка создания meta:", err)
 	}
 
-	// === Тексты документов (с плейсхолдерами) ===
-	docs := map[string]string{
-		"eula_text": `ЛИЦЕНЗИОННОЕ СОГЛАШЕНИЕ (EULA)
-
-		Настоящее Лицензионное соглашение заключается между {{company_name}} ({{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}) и вами.
-
-		1. Предоставление лицензии
-		Правообладатель предоставляет неисключительную лицензию на использование ПО.
-
-		2. Активация и рабочие места
-		Лицензия активируется на конкретных рабочих местах. Перечень активных рабочих мест приведён в Приложении ниже.
-
-		3. Ограничения
-		• Запрещается передача ключа третьим лицам
-		• Запрещается превышение лимита активаций
-
-		4. Срок действия
-		До даты истечения ключа или отзыва Правообладателем.
-
-		5. Поддержка
-		Email: {{support_email}}
-
-		Настоящее соглашение вступает в силу с момента активации.
-		{{company_name}}, {{year}} г.`,
-
-				"privacy_policy": `ПОЛИТИКА КОНФИДЕНЦИАЛЬНОСТИ
-
-		{{company_name}} уважает вашу конфиденциальность.
-
-		1. Собираемые данные
-		• Лицензионный ключ
-		• MAC-адрес устройства
-		• IP при активации
-
-		2. Цели обработки
-		• Проверка лицензии
-		• Формирование отчётов
-		• Защита от пиратства
-
-		3. Хранение
-		Данные хранятся 3 года на защищённых серверах в РФ.
-
-		4. Контакты
-		{{support_email}}
-
-		Актуально на {{year}} год.`,
-
-				"offer_text": `ПУБЛИЧНАЯ ОФЕРТА
-
-		{{legal_name}}, в лице директора, публикует оферту.
-
-		1. Предмет
-		Предоставление неисключительных лицензий на ПО LicenseCore.
-
-		2. Стоимость
-		Указана на сайте {{website}}
-
-		3. Акцепт
-		С момента оплаты.
-
-		Реквизиты:
-		{{legal_name}}
-		ИНН {{inn}} • ОГРН {{ogrn}}
-		{{legal_address}}
-		{{support_email}}`,
-
-				"payment_text": `ПЛАТЁЖНОЕ ПОРУЧЕНИЕ № ___ от {{current_date}}
-
-		Плательщик: {{company_name}}
-		ИНН {{inn}}
-
-		Получатель: {{legal_name}}
-		ИНН {{inn}}
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS ip_bans (
+			ip TEXT PRIMARY KEY,
+			reason TEXT,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			expires_at DATETIME
+		);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания ip_bans:", err)
+	}
 
-		Назначение платежа: Оплата лицензий LicenseCore
-		Сумма: ____________________ руб.
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_events (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			at TEXT NOT NULL,
+			actor TEXT NOT NULL,
+			action TEXT NOT NULL,
+			entity_type TEXT NOT NULL,
+			entity_id TEXT NOT NULL,
+			before_json TEXT,
+			after_json TEXT,
+			diff_json TEXT,
+			ip TEXT,
+			prev_hash TEXT NOT NULL,
+			hash TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_entity ON audit_events (entity_type, entity_id);
+		CREATE INDEX IF NOT EXISTS idx_audit_at ON audit_events (at);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания audit_events:", err)
+	}
+	if err := initAuditHead(); err != nil {
+		log.Fatal("Ошибка записи головы журнала аудита:", err)
+	}
 
-		Директор ____________________ /Иванов И.И./`,
+	// Выданные документы по лицензиям: номер сквозной в пределах типа
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS license_documents (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			type TEXT NOT NULL,
+			license_id INTEGER NOT NULL,
+			seq INTEGER NOT NULL,
+			number TEXT NOT NULL,
+			issued_at TEXT NOT NULL,
+			UNIQUE (type, license_id),
+			UNIQUE (type, seq)
+		);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания license_documents:", err)
+	}
 
-				"invoice_text": `ТОВАРНАЯ НАКЛАДНАЯ № ___ от {{current_date}}
+	// Редакции юридических документов (см. documents.go)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS document_versions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			doc_key TEXT NOT NULL,
+			locale TEXT NOT NULL DEFAULT 'ru',
+			version INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'draft',
+			body TEXT NOT NULL,
+			effective_from TEXT,
+			author TEXT NOT NULL,
+			comment TEXT,
+			created_at TEXT NOT NULL,
+			published_at TEXT,
+			UNIQUE (doc_key, version)
+		);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания document_versions:", err)
+	}
 
-		Поставщик: {{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}
-		Покупатель: {{company_name}}
+	// Принятые клиентами редакции документов (см. acceptance.go)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS document_acceptances (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			license_id INTEGER NOT NULL,
+			device TEXT NOT NULL DEFAULT '',
+			doc_key TEXT NOT NULL,
+			locale TEXT NOT NULL DEFAULT 'ru',
+			version INTEGER NOT NULL,
+			accepted_at TEXT NOT NULL,
+			ip TEXT,
+			user_agent TEXT,
+			UNIQUE (license_id, doc_key, version, device)
+		);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания document_acceptances:", err)
+	}
 
-		№ | Наименование                          | Кол-во | Цена     | Сумма
-		--|---------------------------------------|--------|----------|----------
-		1 | Неисключительная лицензия LicenseCore | 1      | ______   | ______
+	// Рабочие места: кабинеты и устройства (см. workplaces.go)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS rooms (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			position INTEGER NOT NULL DEFAULT 0,
+			version INTEGER NOT NULL DEFAULT 1
+		);
+		CREATE TABLE IF NOT EXISTS devices (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			room_id INTEGER,
+			name TEXT NOT NULL DEFAULT '',
+			mac TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL DEFAULT 'desktop',
+			x REAL NOT NULL DEFAULT 100,
+			y REAL NOT NULL DEFAULT 100,
+			version INTEGER NOT NULL DEFAULT 1
+		);
+		CREATE INDEX IF NOT EXISTS idx_devices_room ON devices (room_id);
+		CREATE INDEX IF NOT EXISTS idx_devices_mac ON devices (mac);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания rooms/devices:", err)
+	}
 
-		Итого: ______ руб.
+	// На каких устройствах активирована лицензия (см. license_devices.go)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS license_devices (
+			license_id INTEGER NOT NULL,
+			device_id INTEGER NOT NULL,
+			linked_at TEXT NOT NULL,
+			PRIMARY KEY (license_id, device_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_license_devices_device ON license_devices (device_id);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания license_devices:", err)
+	}
 
-		Директор ____________________ /Иванов И.И./`,
+	// Устройства, найденные в арендах DHCP и таблицах ARP (см. discovery_api.go)
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS device_discoveries (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			mac TEXT NOT NULL UNIQUE,
+			ip TEXT NOT NULL DEFAULT '',
+			hostname TEXT NOT NULL DEFAULT '',
+			vendor TEXT NOT NULL DEFAULT '',
+			source TEXT NOT NULL DEFAULT '',
+			seen_at TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			device_id INTEGER
+		);
+	`)
+	if err != nil {
+		log.Fatal("Ошибка создания device_discoveries:", err)
 	}
 
-	for key, text := range docs {
-		_, err = db.Exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", key, text)
-		if err != nil {
-			log.Printf("Ошибка сохранения %s: %v", key, err)
-		}
-	}
-
-	// === Универсальный рендер документов ===
-	renderDoc := func(w http.ResponseWriter, key, title string) {
-		var data struct {
-			CompanyName, LegalName, INN, OGRN, Address, Email, Website, Year, Date string
-		}
-		db.QueryRow("SELECT COALESCE(value,'LicenseCore Inc.') FROM settings WHERE key='company_name'").Scan(&data.CompanyName)
-		db.QueryRow("SELECT COALESCE(value,'ООО «ЛицензКор»') FROM settings WHERE key='legal_name'").Scan(&data.LegalName)
-		db.QueryRow("SELECT COALESCE(value,'7712345678') FROM settings WHERE key='inn'").Scan(&data.INN)
-		db.QueryRow("SELECT COALESCE(value,'1234567890123') FROM settings WHERE key='ogrn'").Scan(&data.OGRN)
-		db.QueryRow("SELECT COALESCE(value,'г. Москва...') FROM settings WHERE key='legal_address'").Scan(&data.Address)
-		db.QueryRow("SELECT COALESCE(value,'support@licensecore.app') FROM settings WHERE key='support_email'").Scan(&data.Email)
-		db.QueryRow("SELECT COALESCE(value,'https://licensecore.app') FROM settings WHERE key='website'").Scan(&data.Website)
-		data.Year = strconv.Itoa(time.Now().Year())
-		data.Date = time.Now().Format("02.01.2006")
-
-		var raw string
-		db.QueryRow("SELECT COALESCE(value,'') FROM settings WHERE key=?", key).Scan(&raw)
-
-		replacer := strings.NewReplacer(
-			"{{company_name}}", data.CompanyName,
-			"{{legal_name}}", data.LegalName,
-			"{{inn}}", data.INN,
-			"{{ogrn}}", data.OGRN,
-			"{{legal_address}}", data.Address,
-			"{{support_email}}", data.Email,
-			"{{website}}", data.Website,
-			"{{year}}", data.Year,
-			"{{current_date}}", data.Date,
-		)
-		content := replacer.Replace(raw)
-
-		html := fmt.Sprintf(`<!DOCTYPE html>
-<html lang="ru"><head><head><meta charset="utf-8"><title>%s</title>
-<style>body{font-family:system-ui,sans-serif;max-width:900px;margin:40px auto;line-height:1.8;color:#1f2937;}
-h1{color:#4f46e5;text-align:center;} table{width:100%%;border-collapse:collapse;margin:40px 0;}
-th,td{border:1px solid #ddd;padding:12px;} th{background:#4f46e5;color:white;}
-.footer{margin-top:80px;text-align:center;color:#666;font-size:0.9em;}</style>
-</head><body><h1>%s</h1><div style="white-space:pre-wrap">%s</div>
-<div class="footer">© %s %s • ИНН %s • %s</div></body></html>`,
-			title, title, content, data.Year, data.CompanyName, data.INN, data.Email)
-
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		fmt.Fprint(w, html)
+	// === Добавляем недостающие колонки (если вдруг старый БД) ===
+	db.Exec("ALTER TABLE licenses ADD COLUMN cost REAL DEFAULT 0")
+	db.Exec("ALTER TABLE licenses ADD COLUMN supplier TEXT")
+	db.Exec("ALTER TABLE licenses ADD COLUMN activated_on TEXT")
+	db.Exec("ALTER TABLE licenses ADD COLUMN product TEXT")
+	db.Exec("ALTER TABLE document_versions ADD COLUMN locale TEXT NOT NULL DEFAULT 'ru'")
+	db.Exec("ALTER TABLE document_acceptances ADD COLUMN locale TEXT NOT NULL DEFAULT 'ru'")
+	db.Exec("ALTER TABLE rooms ADD COLUMN version INTEGER NOT NULL DEFAULT 1")
+	db.Exec("ALTER TABLE devices ADD COLUMN version INTEGER NOT NULL DEFAULT 1")
+	db.Exec("ALTER TABLE devices ADD COLUMN name TEXT NOT NULL DEFAULT ''")
+
+	// === Настройки компании и тексты документов (только недостающие) ===
+	if err := seedSettings(); err != nil {
+		log.Println("Ошибка базовых настроек:", err)
+	}
+	if err := initDocuments(); err != nil {
+		log.Println("Ошибка переноса документов в редакции:", err)
+	}
+	if err := initWorkplaces(); err != nil {
+		log.Println("Ошибка переноса рабочих мест в таблицы:", err)
 	}
 
+	// === Защита проверки ключей от перебора ===
+	initRateLimits(cfg)
+
 	// === Маршруты ===
 	mux := http.NewServeMux()
 	mux.HandleFunc("/api/licenses", handleLicenses)
 	mux.HandleFunc("/api/licenses/", handleLicenseByID)
-	mux.HandleFunc("/api/licenses/validate", handleValidate)
+	mux.Handle("/api/licenses/validate", requireClientCert(validateGuard(http.HandlerFunc(handleValidate))))
+	mux.Handle("/api/licenses/accept", requireClientCert(validateGuard(http.HandlerFunc(handleAccept))))
+	mux.HandleFunc("/api/acceptances", handleAcceptances)
+	mux.HandleFunc("/api/acceptances/report", handleAcceptanceReport)
 	mux.HandleFunc("/api/stats", handleStats)
 	mux.HandleFunc("/api/stats/chart", handleActivationsChart)
 	mux.HandleFunc("/api/licenses/import", handleImport)
+	mux.HandleFunc("/api/licenses/import/report/", handleImportReport)
+	mux.HandleFunc("/api/licenses/import/preview", handleImportPreview)
+	mux.HandleFunc("/api/licenses/import/jobs/", handleImportJob)
 	mux.HandleFunc("/api/licenses/export", handleExport)
+	mux.HandleFunc("/api/dump", handleDump)
+	mux.HandleFunc("/api/restore", handleRestore)
 	mux.HandleFunc("/api/settings", handleSettings)
+	mux.HandleFunc("/api/documents", handleDocuments)
+	mux.HandleFunc("/api/documents/", handleDocument)
+	mux.HandleFunc("/api/settings/reset/", handleSettingsReset)
 	mux.HandleFunc("/api/workplaces", handleWorkplaces)
+	mux.HandleFunc("/api/workplaces/conflicts", handleWorkplaceConflicts)
+	mux.HandleFunc("/api/workplaces/discovery", handleDiscovery)
+	mux.HandleFunc("/api/workplaces/discovery/", handleDiscoveryAction)
+	mux.HandleFunc("/api/rooms", handleRooms)
+	mux.HandleFunc("/api/rooms/", handleRoom)
+	mux.HandleFunc("/api/devices", handleDevices)
+	mux.HandleFunc("/api/devices/", handleDevice)
+	mux.HandleFunc("/api/bans", handleBans)
+	mux.HandleFunc("/api/bans/", handleBans)
+	mux.HandleFunc("/api/ratelimit/stats", handleRateLimitStats)
+	mux.HandleFunc("/api/audit", handleAudit)
+	mux.HandleFunc("/api/audit/verify", handleAuditVerify)
 
 	// Документы
-	mux.HandleFunc("/api/docs/eula", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, "eula_text", "Лицензионное соглашение (EULA)") })
-	mux.HandleFunc("/api/docs/privacy", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, "privacy_policy", "Политика конфиденциальности") })
-	mux.HandleFunc("/api/docs/offer", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, "offer_text", "Публичная оферта") })
-	mux.HandleFunc("/api/docs/payment", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, "payment_text", "Платёжное поручение") })
-	mux.HandleFunc("/api/docs/invoice", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, "invoice_text", "Товарная накладная") })
+	mux.HandleFunc("/api/docs/eula", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, r, "eula_text") })
+	mux.HandleFunc("/api/docs/privacy", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, r, "privacy_policy") })
+	mux.HandleFunc("/api/docs/offer", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, r, "offer_text") })
+	mux.HandleFunc("/api/docs/payment", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, r, "payment_text") })
+	mux.HandleFunc("/api/docs/invoice", func(w http.ResponseWriter, r *http.Request) { renderDoc(w, r, "invoice_text") })
 
 	// Живая таблица рабочих мест в EULA — с фильтром по выбранному кабинету
-	mux.HandleFunc("/api/docs/eula-table", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	
-		// === Загружаем конфиг рабочих мест ===
-		var config struct {
-			Rooms   []string        `json:"rooms"`
-			Devices []map[string]any `json:"devices"`
-		}
-	
-		var jsonStr string
-		if err := db.QueryRow("SELECT COALESCE(value, '') FROM settings WHERE key='workplaces_config'").Scan(&jsonStr); err == nil && jsonStr != "" {
-			json.Unmarshal([]byte(jsonStr), &config)
-		}
-	
-		// === Фильтр по выбранному кабинету (индекс) ===
-		var roomFilter string
-		db.QueryRow("SELECT COALESCE(value, '') FROM settings WHERE key='eula_room_filter'").Scan(&roomFilter)
-	
-		// === Функция: получаем название кабинета по индексу ===
-		getRoomName := func(idx any) string {
-			if idx == nil {
-				return "Без кабинета"
-			}
-			// Приводим к строке
-			var index int
-			switch v := idx.(type) {
-			case float64:
-				index = int(v)
-			case string:
-				fmt.Sscanf(v, "%d", &index)
-			default:
-				return "Неизвестно"
-			}
-	
-			if index >= 0 && index < len(config.Rooms) {
-				return config.Rooms[index]
-			}
-			return fmt.Sprintf("Кабинет %d", index)
-		}
-	
-		html := `<div style="margin:60px auto;padding:40px;background:#f8f9ff;border-radius:20px;border:3px solid #6366f1;font-family:system-ui,sans-serif;">
-			<h2 style="text-align:center;color:#6366f1;font-size:28px;margin-bottom:30px;">
-				Приложение: Активные рабочие места
-			</h2>`
-	
-		var devicesToShow []map[string]any
-	
-		for _, dev := range config.Devices {
-			roomIDRaw := dev["roomId"]
-			var roomIDStr string
-			switch v := roomIDRaw.(type) {
-			case float64:
-				roomIDStr = fmt.Sprintf("%.0f", v)
-			case string:
-				roomIDStr = v
-			default:
-				roomIDStr = ""
-			}
-	
-			if roomFilter != "" && roomIDStr != roomFilter {
-				continue
-			}
-	
-			devicesToShow = append(devicesToShow, dev)
-		}
-	
-		if len(devicesToShow) == 0 {
-			msg := "Нет активных устройств"
-			if roomFilter != "" && len(config.Rooms) > 0 {
-				idx, _ := strconv.Atoi(roomFilter)
-				if idx >= 0 && idx < len(config.Rooms) {
-					msg = fmt.Sprintf("В кабинете <strong>%s</strong> нет активных устройств", config.Rooms[idx])
-				}
-			}
-			html += `<p style="text-align:center;color:#64748b;font-size:18px;">` + msg + `</p></div>`
-		} else {
-			html += `<table style="width:100%;border-collapse:collapse;background:white;border-radius:12px;overflow:hidden;box-shadow:0 10px 30px rgba(0,0,0,0.1);">
-				<thead><tr style="background:#6366f1;color:white;">
-					<th style="padding:16px;">Кабинет</th>
-					<th style="padding:16px;">MAC-адрес</th>
-					<th style="padding:16px;">Тип</th>
-					<th style="padding:16px;">Статус</th>
-				</tr></thead><tbody>`
-	
-			for _, d := range devicesToShow {
-				mac := "—"
-				if m, ok := d["mac"]; ok && m != nil {
-					mac = fmt.Sprintf("%v", m)
-					if mac == "" || mac == "<nil>" {
-						mac = "—"
-					}
-				}
-	
-				status, color := "Не проверен", "#64748b"
-				if s, ok := d["status"].(string); ok {
-					if s == "active" {
-						status, color = "Активен", "#22c55e"
-					} else if s == "expired" {
-						status, color = "Истёк", "#ef4444"
-					}
-				}
-	
-				typ := "Неизвестно"
-				if t, ok := d["type"].(string); ok {
-					switch t {
-					case "desktop": typ = "ПК"
-					case "laptop": typ = "Ноутбук"
-					case "tablet": typ = "Планшет"
-					case "server": typ = "Сервер"
-					}
-				}
-	
-				roomName := getRoomName(d["roomId"])
-	
-				html += fmt.Sprintf(`<tr>
-					<td style="padding:14px;font-weight:bold;">%s</td>
-					<td style="padding:14px;font-family:monospace;background:#f0e6ff;">%s</td>
-					<td style="padding:14px;">%s</td>
-					<td style="padding:14px;font-weight:bold;color:%s;">%s</td>
-				</tr>`, roomName, mac, typ, color, status)
-			}
-	
-			html += `</tbody></table>`
-		}
-	
-		html += `<p style="text-align:center;margin-top:30px;color:#64748b;font-size:14px;">
-			Сформировано: <strong>` + time.Now().Format("02.01.2006 в 15:04") + `</strong>
-		</p></div>`
-	
-		fmt.Fprint(w, html)
-	})
+	mux.HandleFunc("/api/docs/eula-table", handleEulaTable)
 
-	fmt.Println("Сервер запущен → http://localhost:8080")
-	log.Fatal(http.ListenAndServe(":8080", corsMiddleware(mux)))
-}
-
-func handleWorkplaces(w http.ResponseWriter, r *http.Request) {
-    w.Header().Set("Content-Type", "application/json")
-    w.Header().Set("Access-Control-Allow-Origin", "*")
+	srv := newHTTPServer(cfg, corsMiddleware(mux))
+	servers := []*http.Server{srv}
+	scheme := "http"
 
-    if r.Method == "GET" {
-        var config struct {
-            Rooms   []string        `json:"rooms"`
-            Devices []map[string]any `json:"devices"`
-        }
-
-        // Загружаем из settings
-        var jsonStr string
-        db.QueryRow("SELECT value FROM settings WHERE key = 'workplaces_config'").Scan(&jsonStr)
-        if jsonStr != "" {
-            json.Unmarshal([]byte(jsonStr), &config)
-        }
-
-        json.NewEncoder(w).Encode(config)
-        return
-    }
-
-    if r.Method == "POST" {
-        var input struct {
-            Rooms   []string        `json:"rooms"`
-            Devices []map[string]any `json:"devices"`
-        }
-
-        if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-            http.Error(w, "Invalid JSON", 400)
-            return
-        }
-
-        jsonData, _ := json.Marshal(input)
-        _, err := db.Exec(`INSERT OR REPLACE INTO settings (key, value) VALUES ('workplaces_config', ?)`, string(jsonData))
-        if err != nil {
-            http.Error(w, "Save error", 500)
-            return
-        }
-
-        json.NewEncoder(w).Encode(map[string]bool{"success": true})
-        return
-    }
-
-    http.Error(w, "Method not allowed", 405)
-}
+	if cfg.TLSEnabled() {
+		certs, err := newCertReloader(cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA)
+		if err != nil {
+			log.Fatal("Ошибка загрузки TLS:", err)
+		}
+		srv.TLSConfig, err = certs.tlsConfig(cfg.TLSClientAuth)
+		if err != nil {
+			log.Fatal("Ошибка настройки TLS:", err)
+		}
+		goBackground(func(ctx context.Context) { certs.watch(ctx, cfg.TLSReloadInterval) })
+		scheme = "https"
 
-// CORS
-func corsMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
-		if r.Method == "OPTIONS" {
-			w.WriteHeader(http.StatusNoContent)
-			return
+		if cfg.HTTPRedirectAddr != "" {
+			servers = append(servers, newRedirectServer(cfg))
+			fmt.Println("Редирект HTTP → HTTPS с " + cfg.HTTPRedirectAddr)
 		}
-		next.ServeHTTP(w, r)
-	})
+	}
+
+	fmt.Println("Сервер запущен → " + scheme + "://localhost" + cfg.Addr)
+	if err := serve(servers...); err != nil {
+		log.Println("Ошибка сервера:", err)
+	}
+	closeDB()
+	log.Println("Сервер остановлен")
 }
 
 // === СПИСОК И СОЗДАНИЕ ЛИЦЕНЗИЙ ===
@@ -472,33 +357,21 @@ func handleLicenses(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		rows, err := db.Query(`SELECT 
-			id, key, description, expiry_date, max_uses, current_uses, created_at,
-			COALESCE(cost, 0) as cost,
-			COALESCE(supplier, '') as supplier,
-			COALESCE(activated_on, '') as activated_on
-		FROM licenses ORDER BY created_at DESC`)
+		filter, err := parseLicenseFilter(r.URL.Query())
+		if err != nil {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+		rows, err := queryLicenses(filter)
 		if err != nil {
-			http.Error(w, "DB error", 500)
+			httpError(w, r, "err.db", 500)
 			return
 		}
 		defer rows.Close()
 
 		var licenses []License
 		for rows.Next() {
-			var l License
-			err := rows.Scan(
-				&l.ID,
-				&l.Key,
-				&l.Description,
-				&l.ExpiryDate,
-				&l.MaxUses,
-				&l.CurrentUses,
-				&l.CreatedAt,
-				&l.Cost,
-				&l.Supplier,
-				&l.ActivatedOn,
-			)
+			l, err := scanLicense(rows)
 			if err != nil {
 				continue
 			}
@@ -513,19 +386,33 @@ func handleLicenses(w http.ResponseWriter, r *http.Request) {
 			MaxUses     int     `json:"max_uses"`
 			Cost        float64 `json:"cost"`
 			Supplier    string  `json:"supplier"`
+			Product     string  `json:"product"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid JSON", 400)
+			httpError(w, r, "err.invalid_json", 400)
 			return
 		}
 	
 		key := generateKey()
-		_, err := db.Exec(`INSERT INTO licenses 
-			(key, description, expiry_date, max_uses, cost, supplier) 
-			VALUES (?, ?, ?, ?, ?, ?)`,
-			key, input.Description, input.ExpiryDate, input.MaxUses, input.Cost, input.Supplier)
+		tx, err := db.Begin()
 		if err != nil {
-			http.Error(w, "Ошибка создания", 500)
+			httpError(w, r, "err.create", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec(`INSERT INTO licenses 
+			(key, description, expiry_date, max_uses, cost, supplier, product) 
+			VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			key, input.Description, input.ExpiryDate, input.MaxUses, input.Cost, input.Supplier, input.Product)
+		if err == nil {
+			err = auditLicenseCreated(tx, r, res, "license.create")
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.create", 500)
 			return
 		}
 	
@@ -533,7 +420,7 @@ func handleLicenses(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"key": key})
 
 	default:
-		http.Error(w, "Method not allowed", 405)
+		httpError(w, r, "err.method", 405)
 	}
 }
 
@@ -541,7 +428,15 @@ func handleLicenses(w http.ResponseWriter, r *http.Request) {
 func handleLicenseByID(w http.ResponseWriter, r *http.Request) {
 	id := strings.TrimPrefix(r.URL.Path, "/api/licenses/")
 	if id == "" {
-		http.Error(w, "ID required", 400)
+		httpError(w, r, "err.id_required", 400)
+		return
+	}
+	if licenseID, docType, ok := strings.Cut(id, "/docs/"); ok {
+		handleLicenseDoc(w, r, licenseID, docType)
+		return
+	}
+	if licenseID, ok := strings.CutSuffix(id, "/devices"); ok {
+		handleLicenseDevices(w, r, licenseID)
 		return
 	}
 
@@ -552,17 +447,79 @@ func handleLicenseByID(w http.ResponseWriter, r *http.Request) {
 			ExpiryDate  string `json:"expiry_date"`
 			MaxUses     int    `json:"max_uses"`
 		}
-		json.NewDecoder(r.Body).Decode(&input)
-		db.Exec("UPDATE licenses SET description = ?, expiry_date = ?, max_uses = ? WHERE id = ?",
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		before, err := licenseSnapshot(tx, id)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if before == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		_, err = tx.Exec("UPDATE licenses SET description = ?, expiry_date = ?, max_uses = ? WHERE id = ?",
 			input.Description, input.ExpiryDate, input.MaxUses, id)
+		if err == nil {
+			var after *License
+			if after, err = licenseSnapshot(tx, id); err == nil {
+				err = recordAudit(tx, r, "license.update", "license", id, before, after)
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
 		w.WriteHeader(200)
 
 	case "DELETE":
-		db.Exec("DELETE FROM licenses WHERE id = ?", id)
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		before, err := licenseSnapshot(tx, id)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if before == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		_, err = tx.Exec("DELETE FROM licenses WHERE id = ?", id)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM license_devices WHERE license_id = ?", id)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "license.delete", "license", id, before, nil)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
 		w.WriteHeader(200)
 
 	default:
-		http.Error(w, "Method not allowed", 405)
+		httpError(w, r, "err.method", 405)
 	}
 }
 
@@ -570,13 +527,17 @@ func handleLicenseByID(w http.ResponseWriter, r *http.Request) {
 func handleValidate(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	if r.Method != "POST" {
-		http.Error(w, "Only POST", 405)
+		httpError(w, r, "err.method", 405)
 		return
 	}
 
-	var input struct{ Key string `json:"key"` }
+	var input struct {
+		Key    string         `json:"key"`
+		Device string         `json:"device"` // имя устройства для журнала и принятия документов
+		Accept map[string]int `json:"accept"` // принимаемые редакции: {"eula_text": 3}
+	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": "invalid json"})
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": tr(r, "err.invalid_json"), "code": codeInvalidJSON})
 		return
 	}
 
@@ -587,11 +548,13 @@ func handleValidate(w http.ResponseWriter, r *http.Request) {
 		Scan(&l.ID, &l.ExpiryDate, &l.MaxUses, &l.CurrentUses)
 
 	if err == sql.ErrNoRows {
-		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": "Ключ не найден"})
+		rateMetrics.keyNotFound.Add(1)
+		validateLockout.fail(clientIP(r))
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": tr(r, "validate.not_found"), "code": codeKeyNotFound})
 		return
 	}
 	if err != nil {
-		http.Error(w, "DB error", 500)
+		httpError(w, r, "err.db", 500)
 		return
 	}
 
@@ -602,26 +565,60 @@ func handleValidate(w http.ResponseWriter, r *http.Request) {
 	}
 	expiry, _ := time.Parse("2006-01-02", expStr)
 	if time.Now().After(expiry.Add(24*time.Hour - time.Second)) {
-		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": "Срок истёк"})
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": tr(r, "validate.expired"), "code": codeExpired})
 		return
 	}
 	if l.CurrentUses >= l.MaxUses {
-		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": "Лимит исчерпан"})
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": tr(r, "validate.limit"), "code": codeLimitReached})
+		return
+	}
+	// Некорректное принятие не должно тратить активацию — проверяем заранее
+	device := strings.TrimSpace(input.Device)
+	if err := checkAcceptance(db, input.Accept); err != nil {
+		if !isInputError(err) {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": errorText(r, err), "code": codeInvalidAcceptance})
 		return
 	}
 
 	// Атомарная активация + лог
-	tx, _ := db.Begin()
-	tx.Exec("UPDATE licenses SET current_uses = current_uses + 1 WHERE id = ?", l.ID)
-	tx.Exec("INSERT INTO activation_log (license_key) VALUES (?)", key)
-	tx.Commit()
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+	_, err = tx.Exec("UPDATE licenses SET current_uses = current_uses + 1 WHERE id = ?", l.ID)
+	if err == nil {
+		_, err = tx.Exec("INSERT INTO activation_log (license_key, device_name) VALUES (?, NULLIF(?, ''))", key, device)
+	}
+	if err == nil {
+		err = recordAudit(tx, r, "license.activate", "license", strconv.Itoa(l.ID),
+			map[string]int{"current_uses": l.CurrentUses}, map[string]int{"current_uses": l.CurrentUses + 1})
+	}
+	if err == nil && len(input.Accept) > 0 {
+		_, err = recordAcceptances(tx, r, l.ID, device, input.Accept)
+	}
+	if err == nil {
+		err = activateOnDevice(tx, int64(l.ID), device)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
 
 	newUses := l.CurrentUses + 1
 	log.Printf("[SUCCESS] Активирован ключ %s (%d/%d)", key, newUses, l.MaxUses)
 
 	json.NewEncoder(w).Encode(map[string]any{
-		"valid":          true,
-		"remaining_uses": l.MaxUses - newUses,
+		"valid":               true,
+		"remaining_uses":      l.MaxUses - newUses,
+		"acceptance_required": pendingAcceptance(l.ID, device, requestLang(r)),
 	})
 }
 
@@ -632,7 +629,7 @@ func generateKey() string {
 // === СТАТИСТИКА ===
 func handleStats(w http.ResponseWriter, r *http.Request) {
     if r.Method != "GET" {
-        http.Error(w, "Only GET", 405)
+        httpError(w, r, "err.method", 405)
         return
     }
     w.Header().Set("Content-Type", "application/json")
@@ -664,7 +661,7 @@ func handleStats(w http.ResponseWriter, r *http.Request) {
 // === ГРАФИК АКТИВАЦИЙ  ===
 func handleActivationsChart(w http.ResponseWriter, r *http.Request) {
     if r.Method != "GET" {
-        http.Error(w, "Only GET", 405)
+        httpError(w, r, "err.method", 405)
         return
     }
     w.Header().Set("Content-Type", "application/json")
@@ -697,173 +694,6 @@ func handleActivationsChart(w http.ResponseWriter, r *http.Request) {
     json.NewEncoder(w).Encode(data)
 }
 
-// === ИМПОРТ CSV + XLSX ===
-func handleImport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "POST" {
-		http.Error(w, "Only POST", 405)
-		return
-	}
-
-	file, _, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Файл обязателен", 400)
-		return
-	}
-	defer file.Close()
-
-	format := r.FormValue("format")
-	if format != "csv" && format != "xlsx" {
-		http.Error(w, "format=csv или xlsx", 400)
-		return
-	}
-
-	data, _ := io.ReadAll(file)
-	var imported, skipped int
-	if format == "csv" {
-		imported, skipped = importCSV(data)
-	} else {
-		imported, skipped = importXLSX(data)
-	}
-
-	json.NewEncoder(w).Encode(map[string]any{
-		"success":  true,
-		"imported": imported,
-		"skipped":  skipped,
-		"message":  fmt.Sprintf("Добавлено: %d, пропущено: %d", imported, skipped),
-	})
-}
-
-type ImportLicense struct {
-	Description string `csv:"description"`
-	ExpiryDate  string `csv:"expiry_date"`
-	MaxUses     int    `csv:"max_uses"`
-}
-
-func importCSV(data []byte) (int, int) {
-	var list []*ImportLicense
-	if err := gocsv.UnmarshalBytes(data, &list); err != nil {
-		log.Println("CSV error:", err)
-		return 0, 0
-	}
-	return importLicenses(list)
-}
-
-func importXLSX(data []byte) (int, int) {
-	f, err := excelize.OpenReader(bytes.NewReader(data))
-	if err != nil {
-		log.Println("XLSX error:", err)
-		return 0, 0
-	}
-	defer f.Close()
-
-	rows, _ := f.GetRows(f.GetSheetName(0))
-	var list []*ImportLicense
-	for i, row := range rows {
-		if i == 0 || len(row) < 3 { // пропуск заголовка и коротких строк
-			continue
-		}
-		maxUses := 5
-		if row[2] != "" {
-			if n, err := strconv.Atoi(row[2]); err == nil && n > 0 {
-				maxUses = n
-			}
-		}
-		list = append(list, &ImportLicense{
-			Description: strings.TrimSpace(row[0]),
-			ExpiryDate:  strings.TrimSpace(row[1]),
-			MaxUses:     maxUses,
-		})
-	}
-	return importLicenses(list)
-}
-
-func importLicenses(items []*ImportLicense) (int, int) {
-	imported, skipped := 0, 0
-	for _, item := range items {
-		if item.Description == "" || item.ExpiryDate == "" || item.MaxUses < 1 {
-			skipped++
-			continue
-		}
-		key := generateKey()
-		_, err := db.Exec("INSERT INTO licenses (key, description, expiry_date, max_uses) VALUES (?, ?, ?, ?)",
-			key, item.Description, item.ExpiryDate, item.MaxUses)
-		if err != nil {
-			skipped++
-		} else {
-			imported++
-		}
-	}
-	return imported, skipped
-}
-
-// === ЭКСПОРТ ЛИЦЕНЗИЙ В CSV И XLSX ===
-func handleExport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != "GET" {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	format := r.URL.Query().Get("format")
-	if format != "csv" && format != "xlsx" {
-		http.Error(w, "format=csv или xlsx", 400)
-		return
-	}
-
-	rows, err := db.Query("SELECT key, description, expiry_date, max_uses, current_uses, created_at FROM licenses ORDER BY created_at DESC")
-	if err != nil {
-		http.Error(w, "DB error", 500)
-		return
-	}
-	defer rows.Close()
-
-	if format == "csv" {
-		w.Header().Set("Content-Type", "text/csv")
-		w.Header().Set("Content-Disposition", "attachment;filename=licenses.csv")
-		fmt.Fprintln(w, "key,description,expiry_date,max_uses,current_uses,created_at")
-		for rows.Next() {
-			var key, desc, exp string
-			var max, cur int
-			var created time.Time
-			rows.Scan(&key, &desc, &exp, &max, &cur, &created)
-			fmt.Fprintf(w, "%s,%s,%s,%d,%d,%s\n", key, desc, exp, max, cur, created.Format("2006-01-02 15:04:05"))
-		}
-		return
-	}
-
-	// === XLSX экспорт через excelize ===
-	f := excelize.NewFile()
-	sheet := "Licenses"
-	f.SetSheetName("Sheet1", sheet)
-
-	// Заголовки
-	headers := []string{"Key", "Description", "Expiry Date", "Max Uses", "Current Uses", "Created At"}
-	for i, h := range headers {
-		cell := string(rune('A'+i)) + "1"
-		f.SetCellValue(sheet, cell, h)
-	}
-
-	// Данные
-	rowIdx := 2
-	for rows.Next() {
-		var key, desc, exp string
-		var max, cur int
-		var created time.Time
-		rows.Scan(&key, &desc, &exp, &max, &cur, &created)
-
-		f.SetCellValue(sheet, fmt.Sprintf("A%d", rowIdx), key)
-		f.SetCellValue(sheet, fmt.Sprintf("B%d", rowIdx), desc)
-		f.SetCellValue(sheet, fmt.Sprintf("C%d", rowIdx), exp)
-		f.SetCellValue(sheet, fmt.Sprintf("D%d", rowIdx), max)
-		f.SetCellValue(sheet, fmt.Sprintf("E%d", rowIdx), cur)
-		f.SetCellValue(sheet, fmt.Sprintf("F%d", rowIdx), created.Format("2006-01-02 15:04:05"))
-		rowIdx++
-	}
-
-	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	w.Header().Set("Content-Disposition", "attachment;filename=licenses.xlsx")
-	f.Write(w)
-}
-
 // === НАСТРОЙКИ — РАБОЧАЯ ВЕРСИЯ С POST ===
 func handleSettings(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -878,6 +708,13 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 			rows.Scan(&k, &v)
 			data[k] = v
 		}
+		// Тексты документов — действующие редакции на языке запроса (хранятся в document_versions)
+		lang := requestLang(r)
+		for key := range docTemplates {
+			if doc, err := currentDocVersion(db, key, lang); err == nil && doc != nil {
+				data[key] = doc.Body
+			}
+		}
 		json.NewEncoder(w).Encode(data)
 
 	case "POST":
@@ -886,20 +723,63 @@ func handleSettings(w http.ResponseWriter, r *http.Request) {
 			delete(input, "eula_table_title") 
 		}
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-			http.Error(w, "Invalid JSON", 400)
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		// Тексты документов — шаблоны на языке запроса: с ошибкой не сохраняем
+		lang := requestLang(r)
+		if err := validateDocTemplates(input, lang); err != nil {
+			httpError(w, r, "doc.template_error", 400, errorText(r, err))
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		defer tx.Rollback()
+		stmt, err := tx.Prepare("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)")
+		if err != nil {
+			httpError(w, r, "err.save", 500)
 			return
 		}
-		tx, _ := db.Begin()
-		stmt, _ := tx.Prepare("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)")
 		defer stmt.Close()
 		for k, v := range input {
-			stmt.Exec(k, v)
+			// Изменённый текст документа публикуется новой редакцией
+			if _, isDoc := docTemplates[k]; isDoc {
+				if err := publishDocText(tx, r, k, lang, v, "Изменено в настройках"); err != nil {
+					httpError(w, r, "err.save", 500)
+					return
+				}
+				continue
+			}
+			// Фронтенд присылает форму целиком — в журнал пишем только изменённое
+			var old sql.NullString
+			tx.QueryRow("SELECT value FROM settings WHERE key = ?", k).Scan(&old)
+			if old.Valid && old.String == v {
+				continue
+			}
+			if _, err := stmt.Exec(k, v); err != nil {
+				httpError(w, r, "err.save", 500)
+				return
+			}
+			var before any
+			if old.Valid {
+				before = map[string]string{"value": old.String}
+			}
+			if err := recordAudit(tx, r, "setting.update", "setting", k, before, map[string]string{"value": v}); err != nil {
+				httpError(w, r, "err.save", 500)
+				return
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			httpError(w, r, "err.save", 500)
+			return
 		}
-		tx.Commit()
 		json.NewEncoder(w).Encode(map[string]bool{"success": true})
 
 	default:
-		http.Error(w, "Method not allowed", 405)
+		httpError(w, r, "err.method", 405)
 	}
 }
 
@@ -0,0 +1,497 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// === ЗАЩИТА ПРОВЕРКИ КЛЮЧЕЙ ОТ ПЕРЕБОРА ===
+// Перед handleValidate запрос проходит: бан-лист → блокировку за подбор →
+// token bucket по IP → token bucket по API-ключу (заголовок X-API-Key).
+
+// Коды ответов проверки ключа
+const (
+	codeKeyNotFound  = "KEY_NOT_FOUND"
+	codeExpired      = "EXPIRED"
+	codeLimitReached = "LIMIT_REACHED"
+	codeInvalidJSON  = "INVALID_JSON"
+	codeRateLimited  = "RATE_LIMITED"
+	codeLockedOut    = "LOCKED_OUT"
+	codeBanned       = "BANNED"
+
+	codeInvalidAcceptance = "INVALID_ACCEPTANCE" // принятие неизвестной или неопубликованной редакции
+)
+
+// === Token bucket на ключ (IP или API-ключ) ===
+type limiterSet struct {
+	limit rate.Limit
+	burst int
+
+	mu sync.Mutex
+	m  map[string]*limiterEntry
+}
+
+type limiterEntry struct {
+	lim  *rate.Limiter
+	seen time.Time
+}
+
+func newLimiterSet(perSecond float64, burst int) *limiterSet {
+	return &limiterSet{limit: rate.Limit(perSecond), burst: burst, m: map[string]*limiterEntry{}}
+}
+
+func (s *limiterSet) allow(key string) bool {
+	s.mu.Lock()
+	e, ok := s.m[key]
+	if !ok {
+		e = &limiterEntry{lim: rate.NewLimiter(s.limit, s.burst)}
+		s.m[key] = e
+	}
+	e.seen = time.Now()
+	s.mu.Unlock()
+	return e.lim.Allow()
+}
+
+// prune удаляет лимитеры, к которым давно не обращались
+func (s *limiterSet) prune(idle time.Duration) {
+	cutoff := time.Now().Add(-idle)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, e := range s.m {
+		if e.seen.Before(cutoff) {
+			delete(s.m, k)
+		}
+	}
+}
+
+func (s *limiterSet) size() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.m)
+}
+
+// === Прогрессивная блокировка за KEY_NOT_FOUND ===
+// После threshold промахов за window IP блокируется на base, каждая следующая
+// блокировка вдвое длиннее (не больше max). Удачные проверки счётчик не
+// сбрасывают: иначе, имея один настоящий ключ, можно перемежать им перебор.
+// Счётчик блокировок сбрасывается после суток без промахов.
+type lockoutTracker struct {
+	threshold int
+	window    time.Duration
+	base, max time.Duration
+
+	mu sync.Mutex
+	m  map[string]*lockoutState
+}
+
+type lockoutState struct {
+	failures    int
+	lockouts    int
+	until       time.Time
+	windowStart time.Time // первый промах в текущем окне
+	lastFail    time.Time
+}
+
+const lockoutForgetAfter = 24 * time.Hour
+
+func newLockoutTracker(threshold int, window, base, max time.Duration) *lockoutTracker {
+	return &lockoutTracker{threshold: threshold, window: window, base: base, max: max, m: map[string]*lockoutState{}}
+}
+
+// blocked возвращает оставшееся время блокировки
+func (t *lockoutTracker) blocked(ip string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.m[ip]
+	if !ok {
+		return 0, false
+	}
+	if left := time.Until(st.until); left > 0 {
+		return left, true
+	}
+	return 0, false
+}
+
+func (t *lockoutTracker) fail(ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	st, ok := t.m[ip]
+	if !ok || now.Sub(st.lastFail) > lockoutForgetAfter {
+		st = &lockoutState{}
+		t.m[ip] = st
+	}
+	st.lastFail = now
+	if now.Sub(st.windowStart) > t.window {
+		st.failures, st.windowStart = 0, now
+	}
+	st.failures++
+	if st.failures < t.threshold {
+		return
+	}
+
+	st.failures, st.windowStart = 0, time.Time{}
+	st.lockouts++
+	d := time.Duration(float64(t.base) * math.Pow(2, float64(st.lockouts-1)))
+	if d > t.max || d <= 0 {
+		d = t.max
+	}
+	st.until = now.Add(d)
+	rateMetrics.lockouts.Add(1)
+	log.Printf("[LOCKOUT] %s заблокирован на %s после %d промахов", ip, d, t.threshold)
+}
+
+func (t *lockoutTracker) prune() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	for ip, st := range t.m {
+		if now.After(st.until) && now.Sub(st.lastFail) > lockoutForgetAfter {
+			delete(t.m, ip)
+		}
+	}
+}
+
+func (t *lockoutTracker) active() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, st := range t.m {
+		if time.Now().Before(st.until) {
+			n++
+		}
+	}
+	return n
+}
+
+// === Бан-лист (таблица ip_bans, кэш в памяти) ===
+type banEntry struct {
+	Value     string     `json:"ip"` // IP или подсеть в нотации CIDR
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+
+	ipNet *net.IPNet
+}
+
+type banList struct {
+	mu      sync.RWMutex
+	entries []banEntry
+}
+
+func parseBanValue(v string) (*net.IPNet, error) {
+	if strings.Contains(v, "/") {
+		_, n, err := net.ParseCIDR(v)
+		return n, err
+	}
+	ip := net.ParseIP(v)
+	if ip == nil {
+		return nil, newLocalizedError("err.bad_ip", v)
+	}
+	bits := 32
+	if ip.To4() == nil {
+		bits = 128
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+func (b *banList) reload() error {
+	rows, err := db.Query(`SELECT ip, COALESCE(reason, ''), created_at, expires_at FROM ip_bans ORDER BY created_at DESC`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var list []banEntry
+	for rows.Next() {
+		var e banEntry
+		var expires sql.NullTime
+		if err := rows.Scan(&e.Value, &e.Reason, &e.CreatedAt, &expires); err != nil {
+			return err
+		}
+		if expires.Valid {
+			e.ExpiresAt = &expires.Time
+		}
+		if e.ipNet, err = parseBanValue(e.Value); err != nil {
+			log.Println("Пропущен бан:", err)
+			continue
+		}
+		list = append(list, e)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.entries = list
+	b.mu.Unlock()
+	return nil
+}
+
+func (b *banList) banned(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	now := time.Now()
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, e := range b.entries {
+		if e.ExpiresAt != nil && now.After(*e.ExpiresAt) {
+			continue
+		}
+		if e.ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *banList) list() []banEntry {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return append([]banEntry(nil), b.entries...)
+}
+
+// === Метрики заблокированных попыток ===
+var rateMetrics struct {
+	blockedBan     atomic.Int64
+	blockedLockout atomic.Int64
+	blockedIP      atomic.Int64
+	blockedAPIKey  atomic.Int64
+	keyNotFound    atomic.Int64
+	lockouts       atomic.Int64
+}
+
+var (
+	validateIPLimiter  *limiterSet
+	validateKeyLimiter *limiterSet
+	validateLockout    *lockoutTracker
+	bans               = &banList{}
+)
+
+func initRateLimits(c Config) {
+	validateIPLimiter = newLimiterSet(c.ValidateIPRate, c.ValidateIPBurst)
+	validateKeyLimiter = newLimiterSet(c.ValidateAPIKeyRate, c.ValidateAPIKeyBurst)
+	validateLockout = newLockoutTracker(c.LockoutThreshold, c.LockoutWindow, c.LockoutBase, c.LockoutMax)
+	if err := bans.reload(); err != nil {
+		log.Println("Ошибка загрузки бан-листа:", err)
+	}
+
+	goBackground(func(ctx context.Context) {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				validateIPLimiter.prune(10 * time.Minute)
+				validateKeyLimiter.prune(10 * time.Minute)
+				validateLockout.prune()
+			}
+		}
+	})
+}
+
+// clientIP — адрес клиента; X-Forwarded-For учитывается только при LM_TRUST_PROXY.
+// Левые записи заголовка пишет сам клиент, поэтому берётся та, что добавил
+// самый дальний из LM_TRUSTED_PROXY_HOPS доверенных прокси, — считая справа
+func clientIP(r *http.Request) string {
+	if cfg.TrustProxy {
+		var hops []string
+		for _, h := range r.Header.Values("X-Forwarded-For") {
+			for _, ip := range strings.Split(h, ",") {
+				if ip = strings.TrimSpace(ip); ip != "" {
+					hops = append(hops, ip)
+				}
+			}
+		}
+		if len(hops) > 0 {
+			return hops[max(len(hops)-max(cfg.TrustedProxyHops, 1), 0)]
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeValidateBlocked(w http.ResponseWriter, status int, code, msg string, retry time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	if retry > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"valid": false, "error": msg, "code": code})
+}
+
+// validateGuard — middleware для /api/licenses/validate
+func validateGuard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+
+		if bans.banned(ip) {
+			rateMetrics.blockedBan.Add(1)
+			writeValidateBlocked(w, http.StatusForbidden, codeBanned, tr(r, "validate.banned"), 0)
+			return
+		}
+		if left, ok := validateLockout.blocked(ip); ok {
+			rateMetrics.blockedLockout.Add(1)
+			writeValidateBlocked(w, http.StatusTooManyRequests, codeLockedOut, tr(r, "validate.locked_out"), left)
+			return
+		}
+		if !validateIPLimiter.allow(ip) {
+			rateMetrics.blockedIP.Add(1)
+			writeValidateBlocked(w, http.StatusTooManyRequests, codeRateLimited, tr(r, "validate.rate_limited"), time.Second)
+			return
+		}
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" && !validateKeyLimiter.allow(apiKey) {
+			rateMetrics.blockedAPIKey.Add(1)
+			writeValidateBlocked(w, http.StatusTooManyRequests, codeRateLimited, tr(r, "validate.rate_limited"), time.Second)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// === БАН-ЛИСТ: API ===
+// GET /api/bans, POST /api/bans {ip, reason, duration}, DELETE /api/bans/{ip}
+func handleBans(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case "GET":
+		json.NewEncoder(w).Encode(bans.list())
+
+	case "POST":
+		var input struct {
+			IP       string `json:"ip"`
+			Reason   string `json:"reason"`
+			Duration string `json:"duration"` // "1h", "30m"; пусто — бессрочно
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		input.IP = strings.TrimSpace(input.IP)
+		if _, err := parseBanValue(input.IP); err != nil {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+		var expires any
+		if input.Duration != "" {
+			d, err := time.ParseDuration(input.Duration)
+			if err != nil || d <= 0 {
+				httpError(w, r, "param.invalid", 400, "duration")
+				return
+			}
+			expires = time.Now().Add(d).UTC()
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		_, err = tx.Exec(`INSERT OR REPLACE INTO ip_bans (ip, reason, created_at, expires_at) VALUES (?, ?, ?, ?)`,
+			input.IP, input.Reason, time.Now().UTC(), expires)
+		if err == nil {
+			err = recordAudit(tx, r, "ban.create", "ban", input.IP, nil, input)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		// Бан уже в БД; если кэш не перечитался, он не действует — сообщаем об этом
+		if err := bans.reload(); err != nil {
+			log.Println("Ошибка загрузки бан-листа:", err)
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		log.Printf("[BAN] %s: %s", input.IP, input.Reason)
+
+		w.WriteHeader(201)
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	case "DELETE":
+		ip := strings.TrimPrefix(r.URL.Path, "/api/bans/")
+		if ip == "" || ip == r.URL.Path {
+			httpError(w, r, "err.ip_required", 400)
+			return
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		res, err := tx.Exec("DELETE FROM ip_bans WHERE ip = ?", ip)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		if err := recordAudit(tx, r, "ban.delete", "ban", ip, map[string]string{"ip": ip}, nil); err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if err := bans.reload(); err != nil {
+			log.Println("Ошибка загрузки бан-листа:", err)
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]bool{"success": true})
+
+	default:
+		httpError(w, r, "err.method", 405)
+	}
+}
+
+// === МЕТРИКИ ОГРАНИЧЕНИЙ ===
+func handleRateLimitStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]int64{
+		"blocked_ban":      rateMetrics.blockedBan.Load(),
+		"blocked_lockout":  rateMetrics.blockedLockout.Load(),
+		"blocked_ip_rate":  rateMetrics.blockedIP.Load(),
+		"blocked_key_rate": rateMetrics.blockedAPIKey.Load(),
+		"key_not_found":    rateMetrics.keyNotFound.Load(),
+		"lockouts_total":   rateMetrics.lockouts.Load(),
+		"lockouts_active":  int64(validateLockout.active()),
+		"tracked_ips":      int64(validateIPLimiter.size()),
+		"tracked_api_keys": int64(validateKeyLimiter.size()),
+		"bans":             int64(len(bans.list())),
+	})
+}
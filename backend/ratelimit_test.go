@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClientIPForwardedFor(t *testing.T) {
+	saved := cfg
+	defer func() { cfg = saved }()
+
+	r := httptest.NewRequest("POST", "/api/licenses/validate", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+	r.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	r.Header.Add("X-Forwarded-For", "203.0.113.7")
+
+	cases := []struct {
+		trust bool
+		hops  int
+		want  string
+	}{
+		{false, 1, "10.0.0.1"},
+		{true, 1, "203.0.113.7"}, // левые записи подделывает клиент
+		{true, 0, "203.0.113.7"},
+		{true, 2, "2.2.2.2"},
+		{true, 10, "1.1.1.1"},
+	}
+	for _, c := range cases {
+		cfg.TrustProxy, cfg.TrustedProxyHops = c.trust, c.hops
+		if got := clientIP(r); got != c.want {
+			t.Errorf("trust=%v hops=%d: %s, ожидался %s", c.trust, c.hops, got, c.want)
+		}
+	}
+}
+
+func TestLockoutWindow(t *testing.T) {
+	lt := newLockoutTracker(3, time.Minute, time.Minute, time.Hour)
+	for i := 0; i < 2; i++ {
+		lt.fail("198.51.100.1")
+	}
+	if _, ok := lt.blocked("198.51.100.1"); ok {
+		t.Fatal("блокировка раньше порога")
+	}
+	lt.fail("198.51.100.1")
+	if _, ok := lt.blocked("198.51.100.1"); !ok {
+		t.Fatal("нет блокировки после порога")
+	}
+
+	// Промахи за пределами окна не копятся
+	lt.fail("198.51.100.2")
+	lt.fail("198.51.100.2")
+	lt.m["198.51.100.2"].windowStart = time.Now().Add(-2 * time.Minute)
+	lt.fail("198.51.100.2")
+	if _, ok := lt.blocked("198.51.100.2"); ok {
+		t.Fatal("учтены промахи из прошлого окна")
+	}
+}
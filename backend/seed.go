@@ -0,0 +1,419 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// settingsSeedVersion — версия набора значений по умолчанию.
+// Увеличивается, когда в defaultSettings появляются новые ключи:
+// при старте недостающие ключи дописываются один раз, а уже сохранённые
+// администратором значения не трогаются.
+const settingsSeedVersion = 2
+
+// === Значения настроек по умолчанию ===
+var defaultSettings = map[string]string{
+	"company_name":  "LicenseCore Inc.",
+	"legal_name":    "ООО «ЛицензКор»",
+	"inn":           "7712345678",
+	"ogrn":          "1234567890123",
+	"legal_address": "г. Москва, ул. Примерная, д. 10, офис 501",
+	"support_email": "support@licensecore.app",
+	"website":       "https://licensecore.app",
+
+	// === Тексты документов (с плейсхолдерами) ===
+	"eula_text": `ЛИЦЕНЗИОННОЕ СОГЛАШЕНИЕ (EULA)
+
+		Настоящее Лицензионное соглашение заключается между {{company_name}} ({{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}) и вами.
+
+		1. Предоставление лицензии
+		Правообладатель предоставляет неисключительную лицензию на использование ПО.
+
+		2. Активация и рабочие места
+		Лицензия активируется на конкретных рабочих местах. Перечень активных рабочих мест приведён в Приложении ниже.
+
+		3. Ограничения
+		• Запрещается передача ключа третьим лицам
+		• Запрещается превышение лимита активаций
+
+		4. Срок действия
+		До даты истечения ключа или отзыва Правообладателем.
+
+		5. Поддержка
+		Email: {{support_email}}
+
+		Настоящее соглашение вступает в силу с момента активации.
+		{{company_name}}, {{year}} г.`,
+
+	"privacy_policy": `ПОЛИТИКА КОНФИДЕНЦИАЛЬНОСТИ
+
+		{{company_name}} уважает вашу конфиденциальность.
+
+		1. Собираемые данные
+		• Лицензионный ключ
+		• MAC-адрес устройства
+		• IP при активации
+
+		2. Цели обработки
+		• Проверка лицензии
+		• Формирование отчётов
+		• Защита от пиратства
+
+		3. Хранение
+		Данные хранятся 3 года на защищённых серверах в РФ.
+
+		4. Контакты
+		{{support_email}}
+
+		Актуально на {{year}} год.`,
+
+	"offer_text": `ПУБЛИЧНАЯ ОФЕРТА
+
+		{{legal_name}}, в лице директора, публикует оферту.
+
+		1. Предмет
+		Предоставление неисключительных лицензий на ПО LicenseCore.
+
+		2. Стоимость
+		Указана на сайте {{website}}
+
+		3. Акцепт
+		С момента оплаты.
+
+		Реквизиты:
+		{{legal_name}}
+		ИНН {{inn}} • ОГРН {{ogrn}}
+		{{legal_address}}
+		{{support_email}}`,
+
+	"payment_text": `ПЛАТЁЖНОЕ ПОРУЧЕНИЕ № ___ от {{current_date}}
+
+		Плательщик: {{company_name}}
+		ИНН {{inn}}
+
+		Получатель: {{legal_name}}
+		ИНН {{inn}}
+
+		Назначение платежа: Оплата лицензий LicenseCore
+		Сумма: ____________________ руб.
+
+		Директор ____________________ /Иванов И.И./`,
+
+	"invoice_text": `ТОВАРНАЯ НАКЛАДНАЯ № ___ от {{current_date}}
+
+		Поставщик: {{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}
+		Покупатель: {{company_name}}
+
+		№ | Наименование                          | Кол-во | Цена     | Сумма
+		--|---------------------------------------|--------|----------|----------
+		1 | Неисключительная лицензия LicenseCore | 1      | ______   | ______
+
+		Итого: ______ руб.
+
+		Директор ____________________ /Иванов И.И./`,
+
+	// === Документы по конкретной лицензии (/api/licenses/{id}/docs/...) ===
+	"license_invoice_text": `ТОВАРНАЯ НАКЛАДНАЯ № {{.Number}} от {{date .IssuedAt}}
+
+		Поставщик: {{default (legal_name) .License.Supplier}}
+		Покупатель: {{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}
+
+		№ | Наименование | Кол-во | Цена | Сумма
+		--|--------------|--------|------|------
+		1 | Неисключительная лицензия «{{default .License.Description .License.Product}}» | {{.License.MaxUses}} | {{money .SeatPrice}} | {{money .License.Cost}}
+
+		Итого: {{money .License.Cost}}
+		Ключ: {{.License.Key}}, срок действия до {{date .License.ExpiryDate}}
+
+		Директор ____________________ /Иванов И.И./`,
+
+	"license_certificate_text": `ЛИЦЕНЗИОННЫЙ СЕРТИФИКАТ № {{.Number}}
+
+		Настоящим {{legal_name}} (ИНН {{inn}}) подтверждает право использования программного обеспечения
+		«{{default .License.Description .License.Product}}».
+
+		Лицензионный ключ: {{.License.Key}}
+		Срок действия: с {{date .License.CreatedAt}} по {{date .License.ExpiryDate}}
+		Количество рабочих мест: {{.License.MaxUses}}
+		{{with .License.Supplier}}Поставщик: {{.}}
+		{{end}}
+		Дата выдачи: {{date .IssuedAt}}
+		{{company_name}}, {{support_email}}`,
+
+	"license_eula_text": `ЛИЦЕНЗИОННОЕ СОГЛАШЕНИЕ (EULA) № {{.Number}}
+
+		Настоящее Лицензионное соглашение заключается между {{company_name}} ({{legal_name}}, ИНН {{inn}}, ОГРН {{ogrn}}) и вами
+		в отношении лицензии {{.License.Key}} на «{{default .License.Description .License.Product}}».
+
+		1. Предоставление лицензии
+		Правообладатель предоставляет неисключительную лицензию на использование ПО не более чем на {{.License.MaxUses}} рабочих местах.
+
+		2. Рабочие места
+		{{range $i, $d := .LicenseDevices}}{{if $i}}, {{end}}{{$d}}{{else}}Лицензия пока не активирована ни на одном устройстве.{{end}}
+
+		3. Ограничения
+		• Запрещается передача ключа третьим лицам
+		• Запрещается превышение лимита активаций
+
+		4. Срок действия
+		До {{date .License.ExpiryDate}} или до отзыва Правообладателем.
+
+		5. Поддержка
+		Email: {{support_email}}
+
+		{{company_name}}, {{date .IssuedAt}}`,
+}
+
+// === Тексты документов на других языках (русские — в defaultSettings) ===
+var defaultDocuments = map[string]map[string]string{
+	"en": {
+		"eula_text": `END USER LICENSE AGREEMENT (EULA)
+
+		This License Agreement is entered into between {{company_name}} ({{legal_name}}, TIN {{inn}}, PSRN {{ogrn}}) and you.
+
+		1. Grant of License
+		The Licensor grants you a non-exclusive license to use the Software.
+
+		2. Activation and Workplaces
+		The license is activated on specific workplaces. The list of active workplaces is given in the Appendix below.
+
+		3. Restrictions
+		• You may not transfer the key to third parties
+		• You may not exceed the activation limit
+
+		4. Term
+		Until the key expires or is revoked by the Licensor.
+
+		5. Support
+		Email: {{support_email}}
+
+		This Agreement takes effect upon activation.
+		{{company_name}}, {{year}}`,
+
+		"privacy_policy": `PRIVACY POLICY
+
+		{{company_name}} respects your privacy.
+
+		1. Data We Collect
+		• License key
+		• Device MAC address
+		• IP address at activation
+
+		2. Purposes of Processing
+		• License verification
+		• Reporting
+		• Piracy protection
+
+		3. Storage
+		Data is kept for 3 years on secure servers in the Russian Federation.
+
+		4. Contacts
+		{{support_email}}
+
+		Effective for {{year}}.`,
+
+		"offer_text": `PUBLIC OFFER
+
+		{{legal_name}}, represented by its director, publishes this offer.
+
+		1. Subject
+		Provision of non-exclusive licenses for the LicenseCore software.
+
+		2. Price
+		As listed on {{website}}
+
+		3. Acceptance
+		Upon payment.
+
+		Details:
+		{{legal_name}}
+		TIN {{inn}} • PSRN {{ogrn}}
+		{{legal_address}}
+		{{support_email}}`,
+
+		"payment_text": `PAYMENT ORDER No. ___ dated {{current_date}}
+
+		Payer: {{company_name}}
+		TIN {{inn}}
+
+		Payee: {{legal_name}}
+		TIN {{inn}}
+
+		Purpose of payment: LicenseCore licenses
+		Amount: ____________________ RUB
+
+		Director ____________________ /I. I. Ivanov/`,
+
+		"invoice_text": `DELIVERY NOTE No. ___ dated {{current_date}}
+
+		Supplier: {{legal_name}}, TIN {{inn}}, PSRN {{ogrn}}
+		Buyer: {{company_name}}
+
+		# | Item                                   | Qty | Price    | Amount
+		--|----------------------------------------|-----|----------|----------
+		1 | Non-exclusive LicenseCore license      | 1   | ______   | ______
+
+		Total: ______ RUB
+
+		Director ____________________ /I. I. Ivanov/`,
+
+		"license_invoice_text": `DELIVERY NOTE No. {{.Number}} dated {{date .IssuedAt}}
+
+		Supplier: {{default (legal_name) .License.Supplier}}
+		Buyer: {{legal_name}}, TIN {{inn}}, PSRN {{ogrn}}
+
+		# | Item | Qty | Price | Amount
+		--|------|-----|-------|-------
+		1 | Non-exclusive license "{{default .License.Description .License.Product}}" | {{.License.MaxUses}} | {{money .SeatPrice}} | {{money .License.Cost}}
+
+		Total: {{money .License.Cost}}
+		Key: {{.License.Key}}, valid until {{date .License.ExpiryDate}}
+
+		Director ____________________ /I. I. Ivanov/`,
+
+		"license_certificate_text": `LICENSE CERTIFICATE No. {{.Number}}
+
+		{{legal_name}} (TIN {{inn}}) hereby confirms the right to use the software
+		"{{default .License.Description .License.Product}}".
+
+		License key: {{.License.Key}}
+		Valid: from {{date .License.CreatedAt}} to {{date .License.ExpiryDate}}
+		Number of workplaces: {{.License.MaxUses}}
+		{{with .License.Supplier}}Supplier: {{.}}
+		{{end}}
+		Issued: {{date .IssuedAt}}
+		{{company_name}}, {{support_email}}`,
+
+		"license_eula_text": `END USER LICENSE AGREEMENT (EULA) No. {{.Number}}
+
+		This License Agreement is entered into between {{company_name}} ({{legal_name}}, TIN {{inn}}, PSRN {{ogrn}}) and you
+		in respect of license {{.License.Key}} for "{{default .License.Description .License.Product}}".
+
+		1. Grant of License
+		The Licensor grants you a non-exclusive license to use the Software on no more than {{.License.MaxUses}} workplaces.
+
+		2. Workplaces
+		{{range $i, $d := .LicenseDevices}}{{if $i}}, {{end}}{{$d}}{{else}}The license has not been activated on any device yet.{{end}}
+
+		3. Restrictions
+		• You may not transfer the key to third parties
+		• You may not exceed the activation limit
+
+		4. Term
+		Until {{date .License.ExpiryDate}} or until revoked by the Licensor.
+
+		5. Support
+		Email: {{support_email}}
+
+		{{company_name}}, {{date .IssuedAt}}`,
+	},
+}
+
+// defaultDocument — текст документа key по умолчанию на языке lang
+func defaultDocument(lang, key string) string {
+	if text, ok := defaultDocuments[lang][key]; ok {
+		return text
+	}
+	return defaultSettings[key]
+}
+
+// seedSettings дописывает отсутствующие ключи из defaultSettings, если
+// сохранённая версия сида меньше settingsSeedVersion. Существующие значения
+// не перезаписываются — для этого есть /api/settings/reset/{key}.
+func seedSettings() error {
+	var applied int
+	err := db.QueryRow("SELECT CAST(value AS INTEGER) FROM meta WHERE key = 'settings_seed_version'").Scan(&applied)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if applied >= settingsSeedVersion {
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for key, value := range defaultSettings {
+		if _, err := tx.Exec("INSERT OR IGNORE INTO settings (key, value) VALUES (?, ?)", key, value); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT OR REPLACE INTO meta (key, value) VALUES ('settings_seed_version', ?)", settingsSeedVersion); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("Настройки по умолчанию: применён сид v%d (был v%d)", settingsSeedVersion, applied)
+	return nil
+}
+
+// === СБРОС НАСТРОЙКИ К ЗНАЧЕНИЮ ПО УМОЛЧАНИЮ ===
+// POST /api/settings/reset/{key}
+func handleSettingsReset(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != "POST" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/api/settings/reset/")
+	value, ok := defaultSettings[key]
+	lang := requestLang(r)
+	if _, isDoc := docTemplates[key]; isDoc {
+		value = defaultDocument(lang, key)
+	}
+	if !ok {
+		httpError(w, r, "settings.no_default", 404)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.save", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	// Текст документа на языке запроса сбрасывается новой редакцией, история сохраняется
+	if _, isDoc := docTemplates[key]; isDoc {
+		err = publishDocText(tx, r, key, lang, value, "Сброс к значению по умолчанию")
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "key": key, "value": value})
+		return
+	}
+
+	var before any
+	var old sql.NullString
+	tx.QueryRow("SELECT value FROM settings WHERE key = ?", key).Scan(&old)
+	if old.Valid {
+		before = map[string]string{"value": old.String}
+	}
+
+	_, err = tx.Exec("INSERT OR REPLACE INTO settings (key, value) VALUES (?, ?)", key, value)
+	if err == nil {
+		err = recordAudit(tx, r, "setting.reset", "setting", key, before, map[string]string{"value": value})
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		httpError(w, r, "err.save", 500)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "key": key, "value": value})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// === Фоновые задачи ===
+// Всё, что работает вне запроса (импорт, очистка и т.п.), запускается через
+// goBackground: при остановке сервера контекст отменяется, и мы дожидаемся
+// завершения задач до закрытия БД.
+var (
+	bgCtx, bgCancel = context.WithCancel(context.Background())
+	bgWG            sync.WaitGroup
+)
+
+func goBackground(fn func(ctx context.Context)) {
+	bgWG.Add(1)
+	go func() {
+		defer bgWG.Done()
+		fn(bgCtx)
+	}()
+}
+
+// stopBackground отменяет фоновые задачи и ждёт их не дольше, чем до ctx.Done().
+func stopBackground(ctx context.Context) error {
+	bgCancel()
+	done := make(chan struct{})
+	go func() {
+		bgWG.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func newHTTPServer(c Config, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.Addr,
+		Handler:           h,
+		ReadTimeout:       c.ReadTimeout,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		WriteTimeout:      c.WriteTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+	}
+}
+
+// serve запускает серверы и блокируется до SIGINT/SIGTERM, после чего
+// перестаёт принимать соединения, дожидается текущих запросов и фоновых задач.
+// Серверы с TLSConfig слушают HTTPS (сертификат берётся из TLSConfig).
+func serve(servers ...*http.Server) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, len(servers))
+	for _, srv := range servers {
+		go func(srv *http.Server) {
+			if srv.TLSConfig != nil {
+				errCh <- srv.ListenAndServeTLS("", "")
+			} else {
+				errCh <- srv.ListenAndServe()
+			}
+		}(srv)
+	}
+
+	var errs []error
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, http.ErrServerClosed) {
+			errs = append(errs, err)
+		}
+	case <-ctx.Done():
+	}
+	stop() // повторный Ctrl+C завершит процесс сразу
+
+	log.Println("Останавливаем сервер...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	for _, srv := range servers {
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := stopBackground(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// closeDB сбрасывает WAL в основной файл и закрывает соединения.
+func closeDB() {
+	if _, err := db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		log.Println("Ошибка checkpoint WAL:", err)
+	}
+	if err := db.Close(); err != nil {
+		log.Println("Ошибка закрытия БД:", err)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strconv"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// === ШАБЛОНЫ ДОКУМЕНТОВ ===
+// Тексты документов (редакции, см. documents.go) — шаблоны Go. HTML-версия
+// рендерится через html/template (значения настроек экранируются), PDF — через
+// text/template.
+//
+//	{{.Company.LegalName}}, {{.Settings.inn}}, {{setting "website"}}
+//	{{range .Licenses}}{{.Key}} до {{date .ExpiryDate}}{{end}}
+//	{{range .Workplaces.Devices}}{{.Room}}: {{.MAC}}{{end}}
+//	{{.License.Key}}, {{.Number}} — в документах по конкретной лицензии
+//	Функции: date, money, upper, lower, default, setting
+//	(date и money форматируют по языку редакции: 31.12.2025 / Dec 31, 2025)
+//
+// Старые плейсхолдеры {{company_name}}, {{inn}}, {{current_date}} и т.д.
+// остаются функциями без аргументов, поэтому сохранённые тексты работают.
+
+// Документы-шаблоны; заголовки — в каталогах сообщений (doc.title.<ключ>)
+var docTemplates = map[string]bool{
+	"eula_text":      true,
+	"privacy_policy": true,
+	"offer_text":     true,
+	"payment_text":   true,
+	"invoice_text":   true,
+
+	// по конкретной лицензии, см. license_docs.go
+	"license_invoice_text":     true,
+	"license_certificate_text": true,
+	"license_eula_text":        true,
+}
+
+// docTitle — заголовок документа key на языке lang
+func docTitle(lang, key string) string {
+	return msg(lang, "doc.title."+key)
+}
+
+// docContext — данные, доступные шаблону. Лицензии и рабочие места
+// загружаются при первом обращении, чтобы простые документы не читали лишнего.
+type docContext struct {
+	Lang     string // язык редакции: от него зависят date, money и служебные надписи
+	Settings map[string]string
+	Company  docRequisites
+	Document *docVersion // выводимая редакция: {{.Document.Version}}, {{date .Document.EffectiveFrom}}
+	License  *License    // документ по конкретной лицензии, иначе nil
+	Number   string      // номер документа по лицензии
+	IssuedAt time.Time   // дата его выдачи
+	Now      time.Time
+
+	licenses   []License
+	workplaces *docWorkplaces
+}
+
+type docWorkplaces struct {
+	Rooms   []string
+	Devices []eulaDeviceRow
+}
+
+func loadDocContext(lang string) *docContext {
+	settings := loadSettings()
+	return &docContext{
+		Lang:     lang,
+		Settings: settings,
+		Company:  requisitesFrom(settings, lang),
+		Now:      time.Now(),
+	}
+}
+
+// Licenses — все лицензии в порядке создания
+func (c *docContext) Licenses() []License {
+	if c.licenses == nil {
+		c.licenses = []License{}
+		rows, err := queryLicenses(licenseFilter{where: []string{"1=1"}, orderBy: "created_at"})
+		if err != nil {
+			return c.licenses
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if l, err := scanLicense(rows); err == nil {
+				l.ExpiryDate = exportDate(l.ExpiryDate)
+				c.licenses = append(c.licenses, l)
+			}
+		}
+	}
+	return c.licenses
+}
+
+// Workplaces — кабинеты и все устройства
+func (c *docContext) Workplaces() *docWorkplaces {
+	if c.workplaces == nil {
+		config := loadWorkplacesConfig()
+		c.workplaces = &docWorkplaces{Rooms: config.roomNames(), Devices: workplaceRows(config, eulaTableQuery{}, c.Lang)}
+	}
+	return c.workplaces
+}
+
+// docFuncs — функции шаблонов; одинаковы для html/template и text/template
+func docFuncs(c *docContext) map[string]any {
+	funcs := map[string]any{
+		"date":    func(v any) string { return templateDate(c.Lang, v) },
+		"money":   func(v float64) string { return templateMoney(c.Lang, v) },
+		"upper":   strings.ToUpper,
+		"lower":   strings.ToLower,
+		"default": templateDefault,
+		"setting": func(key string) string { return c.Settings[key] },
+
+		"year":         func() string { return c.Company.Year },
+		"current_date": func() string { return c.Company.Date },
+	}
+	for name, value := range map[string]string{
+		"company_name":  c.Company.CompanyName,
+		"legal_name":    c.Company.LegalName,
+		"inn":           c.Company.INN,
+		"ogrn":          c.Company.OGRN,
+		"legal_address": c.Company.Address,
+		"support_email": c.Company.Email,
+		"website":       c.Company.Website,
+	} {
+		funcs[name] = func() string { return value }
+	}
+	return funcs
+}
+
+// templateDate — дата из time.Time или строки в формате языка (ДД.ММ.ГГГГ для ru)
+func templateDate(lang string, v any) string {
+	layout := msg(lang, "fmt.date")
+	switch x := v.(type) {
+	case time.Time:
+		return x.Format(layout)
+	case string:
+		if t, err := time.Parse("2006-01-02", exportDate(x)); err == nil {
+			return t.Format(layout)
+		}
+		return x
+	}
+	return ""
+}
+
+// templateMoney — «1 234,50 ₽» (ru), «1,234.50 ₽» (en)
+func templateMoney(lang string, v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	neg := strings.HasPrefix(intPart, "-")
+	intPart = strings.TrimPrefix(intPart, "-")
+
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(msg(lang, "fmt.thousands"))
+		}
+		b.WriteRune(r)
+	}
+	s = b.String() + msg(lang, "fmt.decimal") + frac + " ₽"
+	if neg {
+		return "-" + s
+	}
+	return s
+}
+
+// templateDefault — {{default "—" .Supplier}}: def, если значение пустое
+func templateDefault(def string, v any) any {
+	switch x := v.(type) {
+	case nil:
+		return def
+	case string:
+		if strings.TrimSpace(x) == "" {
+			return def
+		}
+	}
+	return v
+}
+
+// renderDocHTML выполняет шаблон с HTML-экранированием значений
+func renderDocHTML(name, src string, c *docContext) (htmltemplate.HTML, error) {
+	t, err := htmltemplate.New(name).Funcs(docFuncs(c)).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, c); err != nil {
+		return "", err
+	}
+	return htmltemplate.HTML(b.String()), nil
+}
+
+// renderDocText выполняет шаблон без экранирования — для PDF
+func renderDocText(name, src string, c *docContext) (string, error) {
+	t, err := texttemplate.New(name).Funcs(docFuncs(c)).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, c); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// validateDocTemplates проверяет шаблоны документов из сохраняемых настроек
+// на языке lang: синтаксис и пробное выполнение на текущих данных с учётом
+// новых значений — так ловятся и опечатки в полях вроде {{.Company.Inn}}
+func validateDocTemplates(input map[string]string, lang string) error {
+	c := loadDocContext(lang)
+	for k, v := range input {
+		c.Settings[k] = v
+	}
+	c.Company = requisitesFrom(c.Settings, lang)
+	// Шаблоны по лицензии проверяются на образце, чтобы {{.License.Key}} не падал на nil
+	c.License = &License{Key: "XXXX-XXXX-XXXX", Description: "Образец", ExpiryDate: c.Now.Format("2006-01-02"),
+		MaxUses: 1, CreatedAt: c.Now, Cost: 1000, Supplier: "Поставщик", Product: "Продукт"}
+	c.Number, c.IssuedAt = "ОБРАЗЕЦ-000001", c.Now
+	c.Document = &docVersion{Locale: lang, Version: 1, Status: docPublished, EffectiveFrom: today()}
+
+	for key := range docTemplates {
+		src, ok := input[key]
+		if !ok {
+			continue
+		}
+		if _, err := texttemplate.New(key).Funcs(docFuncs(c)).Parse(src); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		t, err := htmltemplate.New(key).Funcs(docFuncs(c)).Parse(src)
+		if err == nil {
+			err = t.Execute(io.Discard, c)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// Страница документа; всё, кроме готового тела, экранируется
+var docPageTemplate = htmltemplate.Must(htmltemplate.New("page").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}"><head><meta charset="utf-8"><title>{{.Title}}</title>
+<style>body{font-family:system-ui,sans-serif;max-width:900px;margin:40px auto;line-height:1.8;color:#1f2937;}
+h1{color:#4f46e5;text-align:center;} table{width:100%;border-collapse:collapse;margin:40px 0;}
+th,td{border:1px solid #ddd;padding:12px;} th{background:#4f46e5;color:white;}
+.footer{margin-top:80px;text-align:center;color:#666;font-size:0.9em;}</style>
+</head><body><h1>{{.Title}}</h1><div style="white-space:pre-wrap">{{.Body}}</div>
+<div class="footer">© {{.Company.Year}} {{.Company.CompanyName}} • {{.TIN}} {{.Company.INN}} • {{.Company.Email}}</div></body></html>
+`))
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Режимы проверки клиентских сертификатов (LM_TLS_CLIENT_AUTH)
+const (
+	clientAuthOff      = "off"      // сертификаты клиентов не запрашиваются
+	clientAuthOptional = "optional" // проверяются, если клиент их предъявил
+	clientAuthValidate = "validate" // как optional, но /api/licenses/validate без сертификата отклоняется
+	clientAuthRequire  = "require"  // обязательны для всех соединений
+)
+
+// === Сертификаты с горячей перезагрузкой ===
+// Файлы перечитываются при изменении (опрос mtime) и по SIGHUP.
+// Уже открытые соединения продолжают работать на старом сертификате,
+// новые рукопожатия получают свежий.
+type certReloader struct {
+	certPath, keyPath, caPath string
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  [3]time.Time
+}
+
+func newCertReloader(certPath, keyPath, caPath string) (*certReloader, error) {
+	c := &certReloader{certPath: certPath, keyPath: keyPath, caPath: caPath}
+	if err := c.reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *certReloader) reload() error {
+	cert, err := tls.LoadX509KeyPair(c.certPath, c.keyPath)
+	if err != nil {
+		return fmt.Errorf("сертификат %s: %w", c.certPath, err)
+	}
+
+	var pool *x509.CertPool
+	if c.caPath != "" {
+		pem, err := os.ReadFile(c.caPath)
+		if err != nil {
+			return fmt.Errorf("CA клиентов %s: %w", c.caPath, err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("CA клиентов %s: не найдено ни одного сертификата", c.caPath)
+		}
+	}
+
+	c.mu.Lock()
+	c.cert = &cert
+	c.clientCAs = pool
+	c.modTimes = c.statAll()
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *certReloader) statAll() [3]time.Time {
+	var t [3]time.Time
+	for i, p := range []string{c.certPath, c.keyPath, c.caPath} {
+		if p == "" {
+			continue
+		}
+		if st, err := os.Stat(p); err == nil {
+			t[i] = st.ModTime()
+		}
+	}
+	return t
+}
+
+func (c *certReloader) changed() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.statAll() != c.modTimes
+}
+
+// watch перечитывает сертификаты по SIGHUP и при изменении файлов.
+// Ошибка загрузки не роняет сервер: остаётся предыдущий сертификат.
+func (c *certReloader) watch(ctx context.Context, interval time.Duration) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			log.Println("SIGHUP: перечитываем TLS-сертификаты")
+		case <-ticker.C:
+			if !c.changed() {
+				continue
+			}
+			log.Println("TLS-сертификаты изменились на диске, перечитываем")
+		}
+		if err := c.reload(); err != nil {
+			log.Println("Ошибка перезагрузки TLS:", err)
+		}
+	}
+}
+
+func (c *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// tlsConfig собирает конфигурацию на каждое рукопожатие, чтобы подхватывать
+// обновлённый пул CA клиентов без перезапуска.
+func (c *certReloader) tlsConfig(clientAuth string) (*tls.Config, error) {
+	var auth tls.ClientAuthType
+	switch clientAuth {
+	case "", clientAuthOff:
+		auth = tls.NoClientCert
+	case clientAuthOptional, clientAuthValidate:
+		auth = tls.VerifyClientCertIfGiven
+	case clientAuthRequire:
+		auth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("неизвестный LM_TLS_CLIENT_AUTH=%q", clientAuth)
+	}
+	if auth != tls.NoClientCert && c.caPath == "" {
+		return nil, errors.New("для проверки клиентских сертификатов нужен LM_TLS_CLIENT_CA")
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			c.mu.RLock()
+			defer c.mu.RUnlock()
+			// Конфигурация из GetConfigForClient заменяет внешнюю целиком, и
+			// "h2", который net/http добавляет во внешнюю, сюда не попадает
+			return &tls.Config{
+				MinVersion:     tls.VersionTLS12,
+				GetCertificate: c.getCertificate,
+				ClientAuth:     auth,
+				ClientCAs:      c.clientCAs,
+				NextProtos:     []string{"h2", "http/1.1"},
+			}, nil
+		},
+	}, nil
+}
+
+// requireClientCert пропускает запрос только с проверенным клиентским
+// сертификатом, если включён режим validate.
+func requireClientCert(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.TLSClientAuth == clientAuthValidate && (r.TLS == nil || len(r.TLS.VerifiedChains) == 0) {
+			httpError(w, r, "err.client_cert", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// === Редирект HTTP → HTTPS ===
+func newRedirectServer(c Config) *http.Server {
+	_, httpsPort, _ := net.SplitHostPort(c.Addr)
+	return &http.Server{
+		Addr:              c.HTTPRedirectAddr,
+		ReadHeaderTimeout: c.ReadHeaderTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		MaxHeaderBytes:    c.MaxHeaderBytes,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := r.Host
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if httpsPort != "" && httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		}),
+	}
+}
@@ -0,0 +1,591 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// === РАБОЧИЕ МЕСТА: КАБИНЕТЫ И УСТРОЙСТВА ===
+// Кабинеты и устройства хранятся в таблицах rooms и devices с постоянными id:
+// удаление кабинета больше не сдвигает roomId остальных устройств. Раньше
+// всё лежало одним JSON в settings.workplaces_config, где roomId — индекс
+// в массиве названий; такой JSON переносится в таблицы при запуске
+// (initWorkplaces) и при восстановлении дампов версий 1–2.
+//
+// У каждой записи есть version — растёт при каждом изменении, по ней
+// работает оптимистичная блокировка (см. workplaces_api.go). Ревизия схемы
+// целиком (meta.workplaces_revision) растёт при любом изменении кабинетов и
+// устройств и защищает POST /api/workplaces.
+//
+// Статус устройства не хранится: он вычисляется из связанных лицензий
+// (license_devices, см. license_devices.go) — active, если хотя бы одна из
+// них действует, expired — если все истекли, "" — если связей нет.
+
+var deviceTypes = []string{"desktop", "laptop", "tablet", "server"}
+
+type workplaceRoom struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Version  int    `json:"version"`
+}
+
+type workplaceDevice struct {
+	ID      int64   `json:"id"`
+	RoomID  *int64  `json:"roomId"` // nil — устройство вне кабинетов
+	Name    string  `json:"name"`   // сетевое имя, например PC-IVANOV
+	MAC     string  `json:"mac"`
+	Type    string  `json:"type"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Version int     `json:"version"`
+
+	// Только для чтения: вычисляются из license_devices
+	Status     string  `json:"status"`
+	LicenseIDs []int64 `json:"licenseIds"`
+}
+
+// label — как устройство попадает в License.ActivatedOn
+func (d workplaceDevice) label() string {
+	if d.Name != "" {
+		return d.Name
+	}
+	return d.MAC
+}
+
+// workplacesConfig — все кабинеты (по position) и устройства
+type workplacesConfig struct {
+	Revision int64             `json:"revision"`
+	Rooms    []workplaceRoom   `json:"rooms"`
+	Devices  []workplaceDevice `json:"devices"`
+}
+
+// workplacesQuerier — общее у *sql.DB и *sql.Tx для чтения списков
+type workplacesQuerier interface {
+	queryRower
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+const (
+	roomColumns   = "id, name, position, version"
+	deviceColumns = "id, room_id, name, mac, type, x, y, version, " + deviceStatusSQL
+)
+
+// deviceStatusSQL — статус устройства по связанным лицензиям; срок действия
+// сравнивается так же, как в фильтре status= списка лицензий
+const deviceStatusSQL = `CASE
+		WHEN NOT EXISTS (SELECT 1 FROM license_devices ld WHERE ld.device_id = devices.id) THEN ''
+		WHEN EXISTS (SELECT 1 FROM license_devices ld JOIN licenses l ON l.id = ld.license_id
+			WHERE ld.device_id = devices.id AND l.expiry_date >= date('now', 'localtime')) THEN 'active'
+		ELSE 'expired' END`
+
+func scanRoom(row interface{ Scan(...any) error }) (*workplaceRoom, error) {
+	var room workplaceRoom
+	if err := row.Scan(&room.ID, &room.Name, &room.Position, &room.Version); err != nil {
+		return nil, err
+	}
+	return &room, nil
+}
+
+func scanDevice(row interface{ Scan(...any) error }) (*workplaceDevice, error) {
+	var d workplaceDevice
+	var roomID sql.NullInt64
+	if err := row.Scan(&d.ID, &roomID, &d.Name, &d.MAC, &d.Type, &d.X, &d.Y, &d.Version, &d.Status); err != nil {
+		return nil, err
+	}
+	if roomID.Valid {
+		d.RoomID = &roomID.Int64
+	}
+	d.LicenseIDs = []int64{}
+	return &d, nil
+}
+
+// workplacesRevision — текущая ревизия схемы; 0, пока ничего не менялось
+func workplacesRevision(q queryRower) int64 {
+	var rev int64
+	q.QueryRow("SELECT CAST(value AS INTEGER) FROM meta WHERE key = 'workplaces_revision'").Scan(&rev)
+	return rev
+}
+
+// bumpWorkplacesRevision вызывается в транзакции каждого изменения рабочих мест
+func bumpWorkplacesRevision(tx *sql.Tx) error {
+	_, err := tx.Exec(`INSERT INTO meta (key, value) VALUES ('workplaces_revision', '1')
+		ON CONFLICT(key) DO UPDATE SET value = CAST(value AS INTEGER) + 1`)
+	return err
+}
+
+func loadWorkplaces(q workplacesQuerier) (workplacesConfig, error) {
+	config := workplacesConfig{Revision: workplacesRevision(q), Rooms: []workplaceRoom{}, Devices: []workplaceDevice{}}
+
+	rows, err := q.Query("SELECT " + roomColumns + " FROM rooms ORDER BY position, id")
+	if err != nil {
+		return config, err
+	}
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			rows.Close()
+			return config, err
+		}
+		config.Rooms = append(config.Rooms, *room)
+	}
+	rows.Close()
+
+	rows, err = q.Query("SELECT " + deviceColumns + " FROM devices ORDER BY id")
+	if err != nil {
+		return config, err
+	}
+	for rows.Next() {
+		d, err := scanDevice(rows)
+		if err != nil {
+			rows.Close()
+			return config, err
+		}
+		config.Devices = append(config.Devices, *d)
+	}
+	rows.Close()
+	byID := map[int64]*workplaceDevice{}
+	for i := range config.Devices {
+		byID[config.Devices[i].ID] = &config.Devices[i]
+	}
+
+	rows, err = q.Query("SELECT device_id, license_id FROM license_devices ORDER BY license_id")
+	if err != nil {
+		return config, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var deviceID, licenseID int64
+		if err := rows.Scan(&deviceID, &licenseID); err != nil {
+			return config, err
+		}
+		if d := byID[deviceID]; d != nil {
+			d.LicenseIDs = append(d.LicenseIDs, licenseID)
+		}
+	}
+	return config, rows.Err()
+}
+
+// loadWorkplacesConfig — для документов и выгрузок: при ошибке пустой список
+func loadWorkplacesConfig() workplacesConfig {
+	config, _ := loadWorkplaces(db)
+	return config
+}
+
+func (c workplacesConfig) room(id int64) (workplaceRoom, bool) {
+	for _, room := range c.Rooms {
+		if room.ID == id {
+			return room, true
+		}
+	}
+	return workplaceRoom{}, false
+}
+
+// roomName — название кабинета устройства; без кабинета — подпись на языке lang
+func (c workplacesConfig) roomName(id *int64, lang string) string {
+	if id == nil {
+		return msg(lang, "room.none")
+	}
+	if room, ok := c.room(*id); ok {
+		return room.Name
+	}
+	return msg(lang, "room.numbered", *id)
+}
+
+// roomNames — названия кабинетов по порядку (для шаблонов)
+func (c workplacesConfig) roomNames() []string {
+	names := make([]string, len(c.Rooms))
+	for i, room := range c.Rooms {
+		names[i] = room.Name
+	}
+	return names
+}
+
+// insertRoom добавляет кабинет; id > 0 сохраняется (восстановление дампа)
+func insertRoom(tx *sql.Tx, room *workplaceRoom) error {
+	if room.Version < 1 {
+		room.Version = 1
+	}
+	var res sql.Result
+	var err error
+	if room.ID > 0 {
+		res, err = tx.Exec("INSERT INTO rooms (id, name, position, version) VALUES (?, ?, ?, ?)",
+			room.ID, room.Name, room.Position, room.Version)
+	} else {
+		res, err = tx.Exec("INSERT INTO rooms (name, position, version) VALUES (?, ?, ?)", room.Name, room.Position, room.Version)
+	}
+	if err != nil {
+		return err
+	}
+	room.ID, err = res.LastInsertId()
+	return err
+}
+
+func insertDevice(tx *sql.Tx, d *workplaceDevice) error {
+	if d.Version < 1 {
+		d.Version = 1
+	}
+	var res sql.Result
+	var err error
+	if d.ID > 0 {
+		res, err = tx.Exec("INSERT INTO devices (id, room_id, name, mac, type, x, y, version) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+			d.ID, d.RoomID, d.Name, d.MAC, d.Type, d.X, d.Y, d.Version)
+	} else {
+		res, err = tx.Exec("INSERT INTO devices (room_id, name, mac, type, x, y, version) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			d.RoomID, d.Name, d.MAC, d.Type, d.X, d.Y, d.Version)
+	}
+	if err != nil {
+		return err
+	}
+	d.ID, err = res.LastInsertId()
+	return err
+}
+
+// normalizeDevice проверяет тип и MAC (см. workplaces_mac.go); пустой тип — desktop
+func normalizeDevice(d *workplaceDevice) error {
+	mac, err := normalizeMAC(d.MAC)
+	if err != nil {
+		return err
+	}
+	d.MAC = mac
+	return normalizeDeviceType(d)
+}
+
+// normalizeStoredDevice — то же для старых данных (перенос и дампы):
+// MAC, который не удалось разобрать, сохраняется как есть, чтобы его
+// можно было найти в отчёте о конфликтах и исправить
+func normalizeStoredDevice(d *workplaceDevice) error {
+	if mac, err := normalizeMAC(d.MAC); err == nil {
+		d.MAC = mac
+	} else {
+		d.MAC = strings.ToUpper(strings.TrimSpace(d.MAC))
+	}
+	return normalizeDeviceType(d)
+}
+
+func normalizeDeviceType(d *workplaceDevice) error {
+	d.Name = strings.TrimSpace(d.Name)
+	d.Type = strings.TrimSpace(d.Type)
+	if d.Type == "" {
+		d.Type = "desktop"
+	}
+	if !containsString(deviceTypes, d.Type) {
+		return newLocalizedError("param.choice", "type", strings.Join(deviceTypes, ", "))
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// === ПЕРЕНОС СТАРОГО JSON ===
+// legacyWorkplaces — формат settings.workplaces_config и дампов версий 1–2
+type legacyWorkplaces struct {
+	Rooms   []string         `json:"rooms"`
+	Devices []map[string]any `json:"devices"`
+}
+
+// legacyRoomIndex — roomId старого формата (число или строка) как индекс
+func legacyRoomIndex(v any) (int, bool) {
+	switch x := v.(type) {
+	case float64:
+		return int(x), true
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(x))
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func legacyNumber(v any, def float64) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(x), 64); err == nil {
+			return f
+		}
+	}
+	return def
+}
+
+// importLegacyWorkplaces добавляет кабинеты и устройства из старого JSON.
+// Устройство с несуществующим индексом кабинета остаётся без кабинета,
+// неизвестный тип становится desktop, неразборчивый MAC сохраняется как
+// есть. Статус, который раньше выставлял
+// фронтенд, не переносится — он вычисляется по связанным лицензиям.
+func importLegacyWorkplaces(tx *sql.Tx, data []byte) (workplacesConfig, error) {
+	var config workplacesConfig
+	var legacy legacyWorkplaces
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return config, err
+	}
+
+	ids := make([]int64, len(legacy.Rooms))
+	for i, name := range legacy.Rooms {
+		room := workplaceRoom{Name: strings.TrimSpace(name), Position: i}
+		if err := insertRoom(tx, &room); err != nil {
+			return config, err
+		}
+		ids[i] = room.ID
+		config.Rooms = append(config.Rooms, room)
+	}
+
+	for _, m := range legacy.Devices {
+		d := workplaceDevice{X: legacyNumber(m["x"], 100), Y: legacyNumber(m["y"], 100)}
+		if idx, ok := legacyRoomIndex(m["roomId"]); ok && idx >= 0 && idx < len(ids) {
+			d.RoomID = &ids[idx]
+		}
+		d.MAC, _ = m["mac"].(string)
+		d.Type, _ = m["type"].(string)
+		if normalizeStoredDevice(&d) != nil {
+			d.Type = "desktop"
+		}
+		if err := insertDevice(tx, &d); err != nil {
+			return config, err
+		}
+		config.Devices = append(config.Devices, d)
+	}
+
+	return config, nil
+}
+
+// migrateWorkplaces переносит settings.workplaces_config в таблицы и удаляет ключ
+func migrateWorkplaces(tx *sql.Tx) error {
+	var data sql.NullString
+	if err := tx.QueryRow("SELECT value FROM settings WHERE key = 'workplaces_config'").Scan(&data); err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if strings.TrimSpace(data.String) != "" {
+		if _, err := importLegacyWorkplaces(tx, []byte(data.String)); err != nil {
+			return fmt.Errorf("workplaces_config: %w", err)
+		}
+	}
+	_, err := tx.Exec("DELETE FROM settings WHERE key = 'workplaces_config'")
+	return err
+}
+
+func initWorkplaces() error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := migrateWorkplaces(tx); err != nil {
+		return err
+	}
+	// Кабинет для таблицы EULA теперь передаётся в запросе (см. eula_table.go)
+	if _, err := tx.Exec("DELETE FROM settings WHERE key = 'eula_room_filter'"); err != nil {
+		return err
+	}
+	if err := normalizeStoredMACs(tx); err != nil {
+		return err
+	}
+	if err := refreshLicenseDevices(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// === API ===
+// GET  /api/workplaces — {"revision":N,"rooms":[{id,name,position,version}],
+//
+//	"devices":[{id,roomId,name,mac,type,x,y,version,status,licenseIds}]}
+//
+// POST /api/workplaces — та же схема целиком. Кабинеты и устройства с
+// известным id обновляются, с id <= 0 или неизвестным — создаются, не
+// попавшие в запрос — удаляются. roomId устройства может ссылаться на
+// временный id нового кабинета из того же запроса. В ответе — сохранённая
+// схема с настоящими id.
+//
+// revision из запроса должна совпадать с текущей, иначе 409: схему успели
+// изменить, и сохранение целиком затёрло бы чужие правки. Без revision
+// (старые клиенты) проверка не выполняется. Для правок по одному кабинету
+// или устройству — /api/rooms и /api/devices.
+func handleWorkplaces(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if r.Method == "GET" {
+		config, err := loadWorkplaces(db)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		json.NewEncoder(w).Encode(config)
+		return
+	}
+
+	if r.Method == "POST" {
+		var input workplacesConfig
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		before, err := loadWorkplaces(tx)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if input.Revision != 0 && input.Revision != before.Revision {
+			httpError(w, r, "workplaces.stale_revision", 409, input.Revision, before.Revision)
+			return
+		}
+		after, err := saveWorkplaces(tx, before, input)
+		if err != nil {
+			if isInputError(err) {
+				http.Error(w, errorText(r, err), 400)
+				return
+			}
+			httpError(w, r, "err.save", 500)
+			return
+		}
+
+		err = recordAudit(tx, r, "workplaces.update", "workplaces", "workplaces", before, after)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "revision": after.Revision, "rooms": after.Rooms, "devices": after.Devices})
+		return
+	}
+
+	httpError(w, r, "err.method", 405)
+}
+
+// saveWorkplaces приводит таблицы к схеме input и возвращает сохранённое;
+// версия растёт только у действительно изменённых записей
+func saveWorkplaces(tx *sql.Tx, current, input workplacesConfig) (workplacesConfig, error) {
+	currentRooms := map[int64]workplaceRoom{}
+	for _, room := range current.Rooms {
+		currentRooms[room.ID] = room
+	}
+	currentDevices := map[int64]workplaceDevice{}
+	for _, d := range current.Devices {
+		currentDevices[d.ID] = d
+	}
+
+	// === Кабинеты: id из запроса → сохранённый id ===
+	roomIDs := map[int64]int64{}
+	keptRooms := map[int64]bool{}
+	for i, room := range input.Rooms {
+		room.Name = strings.TrimSpace(room.Name)
+		if room.Name == "" {
+			return current, newLocalizedError("workplaces.room_name")
+		}
+		room.Position = i
+		requested := room.ID
+		if old, ok := currentRooms[room.ID]; ok {
+			if old.Name != room.Name || old.Position != room.Position {
+				if _, err := tx.Exec("UPDATE rooms SET name = ?, position = ?, version = version + 1 WHERE id = ?",
+					room.Name, room.Position, room.ID); err != nil {
+					return current, err
+				}
+			}
+		} else {
+			room.ID, room.Version = 0, 0
+			if err := insertRoom(tx, &room); err != nil {
+				return current, err
+			}
+		}
+		roomIDs[requested] = room.ID
+		keptRooms[room.ID] = true
+	}
+
+	// === Устройства ===
+	kept := map[int64]bool{}
+	newMACs := map[int64]string{} // новые и изменённые MAC — проверяются на совпадения
+	for _, d := range input.Devices {
+		if err := normalizeDevice(&d); err != nil {
+			return current, err
+		}
+		if d.RoomID != nil {
+			id, ok := roomIDs[*d.RoomID]
+			if !ok {
+				return current, newLocalizedError("workplaces.unknown_room", *d.RoomID)
+			}
+			d.RoomID = &id
+		}
+		old, exists := currentDevices[d.ID]
+		if exists {
+			d.Version = old.Version
+			if !sameDevice(old, d) {
+				if _, err := tx.Exec("UPDATE devices SET room_id = ?, name = ?, mac = ?, type = ?, x = ?, y = ?, version = version + 1 WHERE id = ?",
+					d.RoomID, d.Name, d.MAC, d.Type, d.X, d.Y, d.ID); err != nil {
+					return current, err
+				}
+			}
+		} else {
+			d.ID, d.Version = 0, 0
+			if err := insertDevice(tx, &d); err != nil {
+				return current, err
+			}
+		}
+		if d.MAC != "" && (!exists || d.MAC != old.MAC) {
+			newMACs[d.ID] = d.MAC
+		}
+		kept[d.ID] = true
+	}
+
+	// === Всё, чего нет в запросе, удаляется ===
+	for _, d := range current.Devices {
+		if !kept[d.ID] {
+			if _, err := tx.Exec("DELETE FROM devices WHERE id = ?", d.ID); err != nil {
+				return current, err
+			}
+		}
+	}
+	for _, room := range current.Rooms {
+		if !keptRooms[room.ID] {
+			if _, err := tx.Exec("DELETE FROM rooms WHERE id = ?", room.ID); err != nil {
+				return current, err
+			}
+		}
+	}
+	// Удалённые устройства MAC уже не занимают
+	for id, mac := range newMACs {
+		if err := checkMACFree(tx, mac, id); err != nil {
+			return current, err
+		}
+	}
+	// Связи удалённых устройств и новые имена — в License.ActivatedOn
+	if err := pruneLicenseDevices(tx); err != nil {
+		return current, err
+	}
+	if err := syncActivatedOn(tx); err != nil {
+		return current, err
+	}
+	if err := bumpWorkplacesRevision(tx); err != nil {
+		return current, err
+	}
+	return loadWorkplaces(tx)
+}
+
+// sameDevice — совпадают ли сохраняемые поля (id и версия не сравниваются)
+func sameDevice(a, b workplaceDevice) bool {
+	sameRoom := (a.RoomID == nil && b.RoomID == nil) || (a.RoomID != nil && b.RoomID != nil && *a.RoomID == *b.RoomID)
+	return sameRoom && a.Name == b.Name && a.MAC == b.MAC && a.Type == b.Type && a.X == b.X && a.Y == b.Y
+}
@@ -0,0 +1,563 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// === КАБИНЕТЫ И УСТРОЙСТВА ПО ОДНОМУ ===
+// GET    /api/rooms                    — кабинеты по порядку
+// POST   /api/rooms                    — {"name","position"?} → 201 и кабинет
+// GET    /api/rooms/{id}
+// PUT    /api/rooms/{id}               — {"version","name"?,"position"?}
+// DELETE /api/rooms/{id}?version=N     — вместе с устройствами кабинета
+//
+// GET    /api/devices?room_id=N|none   — устройства (none — вне кабинетов)
+// POST   /api/devices                  — {"roomId","name","mac","type","x","y"} → 201
+// GET    /api/devices/{id}
+// PUT    /api/devices/{id}             — {"version",...} меняет только переданные
+//                                        поля; "roomId": null — убрать из кабинета
+// DELETE /api/devices/{id}?version=N
+//
+// MAC приводится к виду 00:1A:2B:3C:4D:5E, неверный или занятый другим
+// устройством — 400 (workplaces_mac.go).
+//
+// Оптимистичная блокировка: PUT и DELETE передают version, которую видел
+// клиент. Если запись успели изменить — 409 и клиент перечитывает её, без
+// version — 428. Любое изменение увеличивает и ревизию схемы целиком.
+//
+// Связи с лицензиями — /api/devices/{id}/licenses (license_devices.go);
+// status и licenseIds в ответах только для чтения.
+
+func roomByID(q queryRower, id int64) (*workplaceRoom, error) {
+	room, err := scanRoom(q.QueryRow("SELECT "+roomColumns+" FROM rooms WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return room, err
+}
+
+func deviceByID(q workplacesQuerier, id int64) (*workplaceDevice, error) {
+	d, err := scanDevice(q.QueryRow("SELECT "+deviceColumns+" FROM devices WHERE id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.LicenseIDs, err = deviceLicenseIDs(q, id)
+	return d, err
+}
+
+// pathID — id из /api/rooms/{id}[/...] и остаток пути; ответ об ошибке уже
+// отправлен, если !ok
+func pathID(w http.ResponseWriter, r *http.Request, prefix string) (int64, []string, bool) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, prefix), "/"), "/")
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || id < 1 {
+		httpError(w, r, "param.invalid", 400, "id")
+		return 0, nil, false
+	}
+	return id, parts[1:], true
+}
+
+// checkVersion сверяет версию клиента с текущей; ответ уже отправлен, если false
+func checkVersion(w http.ResponseWriter, r *http.Request, expected *int, current int) bool {
+	if expected == nil {
+		httpError(w, r, "workplaces.version_required", http.StatusPreconditionRequired)
+		return false
+	}
+	if *expected != current {
+		httpError(w, r, "workplaces.conflict", http.StatusConflict, *expected, current)
+		return false
+	}
+	return true
+}
+
+// queryVersion — ?version=N для DELETE; nil, если не передана
+func queryVersion(r *http.Request) *int {
+	n, err := strconv.Atoi(r.URL.Query().Get("version"))
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// checkRoomExists — устройство можно поместить только в существующий кабинет
+func checkRoomExists(q queryRower, id *int64) error {
+	if id == nil {
+		return nil
+	}
+	var n int
+	if err := q.QueryRow("SELECT COUNT(*) FROM rooms WHERE id = ?", *id).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return newLocalizedError("workplaces.unknown_room", *id)
+	}
+	return nil
+}
+
+// writeEntity отвечает сохранённой записью
+func writeEntity(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// === Кабинеты ===
+func handleRooms(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		config, err := loadWorkplaces(db)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		writeEntity(w, 200, config.Rooms)
+	case "POST":
+		var in struct {
+			Name     string `json:"name"`
+			Position *int   `json:"position"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		room := workplaceRoom{Name: strings.TrimSpace(in.Name)}
+		if room.Name == "" {
+			httpError(w, r, "workplaces.room_name", 400)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		// Без position — в конец списка
+		if in.Position != nil {
+			room.Position = *in.Position
+		} else {
+			tx.QueryRow("SELECT COALESCE(MAX(position) + 1, 0) FROM rooms").Scan(&room.Position)
+		}
+		err = insertRoom(tx, &room)
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "room.create", "room", strconv.FormatInt(room.ID, 10), nil, room)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.create", 500)
+			return
+		}
+		writeEntity(w, 201, room)
+	default:
+		httpError(w, r, "err.method", 405)
+	}
+}
+
+func handleRoom(w http.ResponseWriter, r *http.Request) {
+	id, rest, ok := pathID(w, r, "/api/rooms/")
+	if !ok {
+		return
+	}
+	if len(rest) > 0 {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+	if r.Method == "GET" {
+		room, err := roomByID(db, id)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if room == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		writeEntity(w, 200, room)
+		return
+	}
+	if r.Method != "PUT" && r.Method != "DELETE" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := roomByID(tx, id)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if before == nil {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		var in struct {
+			Version  *int    `json:"version"`
+			Name     *string `json:"name"`
+			Position *int    `json:"position"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		if !checkVersion(w, r, in.Version, before.Version) {
+			return
+		}
+		after := *before
+		if in.Name != nil {
+			after.Name = strings.TrimSpace(*in.Name)
+			if after.Name == "" {
+				httpError(w, r, "workplaces.room_name", 400)
+				return
+			}
+		}
+		if in.Position != nil {
+			after.Position = *in.Position
+		}
+		after.Version++
+
+		// Транзакция _txlock=immediate: между проверкой версии и записью
+		// никто другой писать не может
+		_, err := tx.Exec("UPDATE rooms SET name = ?, position = ?, version = ? WHERE id = ?",
+			after.Name, after.Position, after.Version, id)
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "room.update", "room", strconv.FormatInt(id, 10), before, after)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		writeEntity(w, 200, after)
+
+	case "DELETE":
+		if !checkVersion(w, r, queryVersion(r), before.Version) {
+			return
+		}
+		// Для журнала — кабинет вместе с удаляемыми устройствами
+		config, err := loadWorkplaces(tx)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		devices := []workplaceDevice{}
+		var licenseIDs []int64
+		for _, d := range config.Devices {
+			if d.RoomID != nil && *d.RoomID == id {
+				devices = append(devices, d)
+				licenseIDs = append(licenseIDs, d.LicenseIDs...)
+			}
+		}
+
+		_, err = tx.Exec("DELETE FROM devices WHERE room_id = ?", id)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM rooms WHERE id = ?", id)
+		}
+		if err == nil {
+			err = pruneLicenseDevices(tx)
+		}
+		if err == nil && len(licenseIDs) > 0 {
+			err = syncActivatedOn(tx, licenseIDs...)
+		}
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "room.delete", "room", strconv.FormatInt(id, 10),
+				map[string]any{"room": before, "devices": devices}, nil)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		w.WriteHeader(200)
+	}
+}
+
+// === Устройства ===
+func handleDevices(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		where, args := "", []any{}
+		switch roomID := r.URL.Query().Get("room_id"); roomID {
+		case "":
+		case "none":
+			where = " WHERE room_id IS NULL"
+		default:
+			id, err := strconv.ParseInt(roomID, 10, 64)
+			if err != nil {
+				httpError(w, r, "param.invalid", 400, "room_id")
+				return
+			}
+			where, args = " WHERE room_id = ?", append(args, id)
+		}
+		rows, err := db.Query("SELECT "+deviceColumns+" FROM devices"+where+" ORDER BY id", args...)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		devices := []workplaceDevice{}
+		for rows.Next() {
+			d, err := scanDevice(rows)
+			if err != nil {
+				rows.Close()
+				httpError(w, r, "err.db", 500)
+				return
+			}
+			devices = append(devices, *d)
+		}
+		rows.Close()
+		for i := range devices {
+			if devices[i].LicenseIDs, err = deviceLicenseIDs(db, devices[i].ID); err != nil {
+				httpError(w, r, "err.db", 500)
+				return
+			}
+		}
+		writeEntity(w, 200, devices)
+
+	case "POST":
+		var d workplaceDevice
+		if err := json.NewDecoder(r.Body).Decode(&d); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		// Связи с лицензиями задаются отдельно; в ответе — [], как в GET и PUT
+		d.ID, d.Version, d.LicenseIDs = 0, 0, []int64{}
+		if err := normalizeDevice(&d); err != nil {
+			http.Error(w, errorText(r, err), 400)
+			return
+		}
+
+		tx, err := db.Begin()
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		defer tx.Rollback()
+
+		err = checkRoomExists(tx, d.RoomID)
+		if err == nil {
+			err = checkMACFree(tx, d.MAC, 0)
+		}
+		if err != nil {
+			if isInputError(err) {
+				http.Error(w, errorText(r, err), 400)
+				return
+			}
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		err = insertDevice(tx, &d)
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "device.create", "device", strconv.FormatInt(d.ID, 10), nil, d)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.create", 500)
+			return
+		}
+		writeEntity(w, 201, d)
+
+	default:
+		httpError(w, r, "err.method", 405)
+	}
+}
+
+// deviceInput — PUT /api/devices/{id}: nil — поле не меняется; roomId
+// хранится как есть, чтобы отличить null («без кабинета») от отсутствия
+type deviceInput struct {
+	Version *int            `json:"version"`
+	RoomID  json.RawMessage `json:"roomId"`
+	Name    *string         `json:"name"`
+	MAC     *string         `json:"mac"`
+	Type    *string         `json:"type"`
+	X       *float64        `json:"x"`
+	Y       *float64        `json:"y"`
+}
+
+// apply переносит переданные поля в d
+func (in deviceInput) apply(d *workplaceDevice) error {
+	if len(in.RoomID) > 0 {
+		var roomID *int64
+		if err := json.Unmarshal(in.RoomID, &roomID); err != nil {
+			return newLocalizedError("param.invalid", "roomId")
+		}
+		d.RoomID = roomID
+	}
+	if in.Name != nil {
+		d.Name = *in.Name
+	}
+	if in.MAC != nil {
+		d.MAC = *in.MAC
+	}
+	if in.Type != nil {
+		d.Type = *in.Type
+	}
+	if in.X != nil {
+		d.X = *in.X
+	}
+	if in.Y != nil {
+		d.Y = *in.Y
+	}
+	return normalizeDevice(d)
+}
+
+func handleDevice(w http.ResponseWriter, r *http.Request) {
+	id, rest, ok := pathID(w, r, "/api/devices/")
+	if !ok {
+		return
+	}
+	if len(rest) > 0 {
+		if rest[0] != "licenses" {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		handleDeviceLicenses(w, r, id, rest[1:])
+		return
+	}
+	if r.Method == "GET" {
+		device, err := deviceByID(db, id)
+		if err != nil {
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		if device == nil {
+			httpError(w, r, "err.not_found", 404)
+			return
+		}
+		writeEntity(w, 200, device)
+		return
+	}
+	if r.Method != "PUT" && r.Method != "DELETE" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := deviceByID(tx, id)
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	if before == nil {
+		httpError(w, r, "err.not_found", 404)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		var in deviceInput
+		if err := json.NewDecoder(r.Body).Decode(&in); err != nil {
+			httpError(w, r, "err.invalid_json", 400)
+			return
+		}
+		if !checkVersion(w, r, in.Version, before.Version) {
+			return
+		}
+		after := *before
+		err := in.apply(&after)
+		if err == nil {
+			err = checkRoomExists(tx, after.RoomID)
+		}
+		// Уже совпадающие MAC из старых данных не мешают двигать устройство
+		if err == nil && after.MAC != before.MAC {
+			err = checkMACFree(tx, after.MAC, id)
+		}
+		if err != nil {
+			if isInputError(err) {
+				http.Error(w, errorText(r, err), 400)
+				return
+			}
+			httpError(w, r, "err.db", 500)
+			return
+		}
+		after.Version++
+
+		_, err = tx.Exec("UPDATE devices SET room_id = ?, name = ?, mac = ?, type = ?, x = ?, y = ?, version = ? WHERE id = ?",
+			after.RoomID, after.Name, after.MAC, after.Type, after.X, after.Y, after.Version, id)
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		// Имя или MAC устройства видны в activated_on связанных лицензий
+		if err == nil && after.label() != before.label() && len(after.LicenseIDs) > 0 {
+			err = syncActivatedOn(tx, after.LicenseIDs...)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "device.update", "device", strconv.FormatInt(id, 10), before, after)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		writeEntity(w, 200, after)
+
+	case "DELETE":
+		if !checkVersion(w, r, queryVersion(r), before.Version) {
+			return
+		}
+		_, err = tx.Exec("DELETE FROM devices WHERE id = ?", id)
+		if err == nil {
+			_, err = tx.Exec("DELETE FROM license_devices WHERE device_id = ?", id)
+		}
+		if err == nil && len(before.LicenseIDs) > 0 {
+			err = syncActivatedOn(tx, before.LicenseIDs...)
+		}
+		if err == nil {
+			err = bumpWorkplacesRevision(tx)
+		}
+		if err == nil {
+			err = recordAudit(tx, r, "device.delete", "device", strconv.FormatInt(id, 10), before, nil)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			httpError(w, r, "err.save", 500)
+			return
+		}
+		w.WriteHeader(200)
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// === MAC-АДРЕСА УСТРОЙСТВ ===
+// MAC хранится в одном виде — 00:1A:2B:3C:4D:5E. На входе принимаются
+// и другие распространённые записи: через дефис (Windows), точками по
+// четыре знака (Cisco) и слитно. Нулевой и групповые адреса (включая
+// широковещательный) сетевой карте принадлежать не могут и отклоняются.
+//
+// Один MAC на двух устройствах при записи запрещён. Уже существующие
+// совпадения (из старых данных), адреса, которые не удалось разобрать при
+// переносе, и MAC, привязанные к нескольким лицензиям, показывает
+//
+// GET /api/workplaces/conflicts — {"duplicates":[...],"licenseConflicts":[...],"invalid":[...]}
+
+var macPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^[0-9A-F]{2}(:[0-9A-F]{2}){5}$`),
+	regexp.MustCompile(`^[0-9A-F]{2}(-[0-9A-F]{2}){5}$`),
+	regexp.MustCompile(`^[0-9A-F]{4}(\.[0-9A-F]{4}){2}$`),
+	regexp.MustCompile(`^[0-9A-F]{12}$`),
+}
+
+// normalizeMAC приводит MAC к виду 00:1A:2B:3C:4D:5E; пустая строка — MAC не задан
+func normalizeMAC(s string) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return "", nil
+	}
+	valid := false
+	for _, p := range macPatterns {
+		if p.MatchString(s) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return "", newLocalizedError("workplaces.bad_mac", s)
+	}
+	digits := strings.NewReplacer(":", "", "-", "", ".", "").Replace(s)
+	raw, _ := hex.DecodeString(digits)
+	// Младший бит первого октета — групповой адрес
+	if raw[0]&1 == 1 || strings.Trim(digits, "0") == "" {
+		return "", newLocalizedError("workplaces.bad_mac", s)
+	}
+	parts := make([]string, 6)
+	for i := range parts {
+		parts[i] = digits[2*i : 2*i+2]
+	}
+	return strings.Join(parts, ":"), nil
+}
+
+// checkMACFree — MAC не занят другим устройством (deviceID — само устройство)
+func checkMACFree(q queryRower, mac string, deviceID int64) error {
+	if mac == "" {
+		return nil
+	}
+	var other int64
+	err := q.QueryRow("SELECT id FROM devices WHERE mac = ? AND id <> ? ORDER BY id LIMIT 1", mac, deviceID).Scan(&other)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return newLocalizedError("workplaces.duplicate_mac", mac, other)
+}
+
+// normalizeStoredMACs приводит к единому виду MAC, сохранённые до проверки
+// на сервере; неразборчивые остаются как есть и попадают в отчёт
+func normalizeStoredMACs(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT id, mac FROM devices WHERE mac <> ''")
+	if err != nil {
+		return err
+	}
+	fixed := map[int64]string{}
+	for rows.Next() {
+		var id int64
+		var mac string
+		if err := rows.Scan(&id, &mac); err != nil {
+			rows.Close()
+			return err
+		}
+		if norm, err := normalizeMAC(mac); err == nil && norm != mac {
+			fixed[id] = norm
+		}
+	}
+	rows.Close()
+	for id, mac := range fixed {
+		if _, err := tx.Exec("UPDATE devices SET mac = ? WHERE id = ?", mac, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// === Отчёт о конфликтах ===
+type conflictDevice struct {
+	ID     int64  `json:"id"`
+	RoomID *int64 `json:"roomId"`
+	Room   string `json:"room"`
+	Name   string `json:"name"`
+	MAC    string `json:"mac"`
+}
+
+type conflictLicense struct {
+	ID         int    `json:"id"`
+	Key        string `json:"key"`
+	Product    string `json:"product"`
+	ExpiryDate string `json:"expiry_date"`
+}
+
+// macConflict — один MAC на нескольких устройствах (duplicates) или
+// несколько лицензий на одном MAC (licenseConflicts). Во втором случае
+// это не обязательно ошибка: у устройства могут быть лицензии разных
+// продуктов, — поэтому продукт показывается рядом с ключом
+type macConflict struct {
+	MAC      string            `json:"mac"`
+	Rooms    []string          `json:"rooms"`
+	Devices  []conflictDevice  `json:"devices"`
+	Licenses []conflictLicense `json:"licenses,omitempty"`
+}
+
+type workplaceConflicts struct {
+	Duplicates       []macConflict    `json:"duplicates"`
+	LicenseConflicts []macConflict    `json:"licenseConflicts"`
+	Invalid          []conflictDevice `json:"invalid"`
+}
+
+func findWorkplaceConflicts(q workplacesQuerier, lang string) (*workplaceConflicts, error) {
+	config, err := loadWorkplaces(q)
+	if err != nil {
+		return nil, err
+	}
+	report := &workplaceConflicts{
+		Duplicates:       []macConflict{},
+		LicenseConflicts: []macConflict{},
+		Invalid:          []conflictDevice{},
+	}
+
+	byMAC := map[string][]workplaceDevice{}
+	var macs []string
+	for _, d := range config.Devices {
+		if d.MAC == "" {
+			continue
+		}
+		if norm, err := normalizeMAC(d.MAC); err != nil || norm != d.MAC {
+			report.Invalid = append(report.Invalid, conflictDevice{d.ID, d.RoomID, config.roomName(d.RoomID, lang), d.Name, d.MAC})
+			continue
+		}
+		if byMAC[d.MAC] == nil {
+			macs = append(macs, d.MAC)
+		}
+		byMAC[d.MAC] = append(byMAC[d.MAC], d)
+	}
+	sort.Strings(macs)
+
+	for _, mac := range macs {
+		devices := byMAC[mac]
+		c := macConflict{MAC: mac, Rooms: []string{}, Devices: []conflictDevice{}}
+		licenseIDs := map[int64]bool{}
+		for _, d := range devices {
+			room := config.roomName(d.RoomID, lang)
+			if !containsString(c.Rooms, room) {
+				c.Rooms = append(c.Rooms, room)
+			}
+			c.Devices = append(c.Devices, conflictDevice{d.ID, d.RoomID, room, d.Name, d.MAC})
+			for _, id := range d.LicenseIDs {
+				licenseIDs[id] = true
+			}
+		}
+		if len(devices) > 1 {
+			report.Duplicates = append(report.Duplicates, c)
+		}
+		if len(licenseIDs) > 1 {
+			ids := make([]int64, 0, len(licenseIDs))
+			for id := range licenseIDs {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+			for _, id := range ids {
+				l, err := licenseSnapshot(q, id)
+				if err != nil {
+					return nil, err
+				}
+				if l != nil {
+					c.Licenses = append(c.Licenses, conflictLicense{l.ID, l.Key, l.Product, exportDate(l.ExpiryDate)})
+				}
+			}
+			report.LicenseConflicts = append(report.LicenseConflicts, c)
+		}
+	}
+	return report, nil
+}
+
+func handleWorkplaceConflicts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		httpError(w, r, "err.method", 405)
+		return
+	}
+	report, err := findWorkplaceConflicts(db, requestLang(r))
+	if err != nil {
+		httpError(w, r, "err.db", 500)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
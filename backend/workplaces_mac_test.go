@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestNormalizeMAC(t *testing.T) {
+	cases := []struct {
+		in, want string
+		ok       bool
+	}{
+		{"00:1a:2b:3c:4d:5e", "00:1A:2B:3C:4D:5E", true},
+		{"00-1A-2B-3C-4D-5E", "00:1A:2B:3C:4D:5E", true}, // Windows
+		{"001a.2b3c.4d5e", "00:1A:2B:3C:4D:5E", true},    // Cisco
+		{"001A2B3C4D5E", "00:1A:2B:3C:4D:5E", true},      // слитно
+		{" 00:1a:2b:3c:4d:5e ", "00:1A:2B:3C:4D:5E", true},
+		{"", "", true},                   // MAC не задан
+		{"01:00:5e:00:00:16", "", false}, // multicast
+		{"ff-ff-ff-ff-ff-ff", "", false}, // широковещательный
+		{"00:00:00:00:00:00", "", false},
+		{"000000000000", "", false},
+		{"00:1a:2b:3c:4d", "", false},
+		{"00:1a:2b-3c:4d:5e", "", false},
+		{"0g:1a:2b:3c:4d:5e", "", false},
+	}
+	for _, c := range cases {
+		got, err := normalizeMAC(c.in)
+		if (err == nil) != c.ok || got != c.want {
+			t.Errorf("normalizeMAC(%q) = %q, %v; ожидался %q, ok=%v", c.in, got, err, c.want, c.ok)
+		}
+	}
+}
@@ -407,6 +407,11 @@ const LicenseManager = () => {
                       <td className={styles.tableTd}>
                         <button onClick={() => startEdit(l)} className={styles.editBtn}>Изменить</button>
                         <button onClick={() => handleDelete(l.id)} className={styles.deleteBtn}>Удалить</button>
+                        <div className="flex gap-2 mt-2 text-xs">
+                          <a href={`/api/licenses/${l.id}/docs/invoice?format=pdf`} target="_blank" rel="noreferrer" className="text-indigo-600 hover:underline">Накладная</a>
+                          <a href={`/api/licenses/${l.id}/docs/certificate?format=pdf`} target="_blank" rel="noreferrer" className="text-indigo-600 hover:underline">Сертификат</a>
+                          <a href={`/api/licenses/${l.id}/docs/eula`} target="_blank" rel="noreferrer" className="text-indigo-600 hover:underline">EULA</a>
+                        </div>
                       </td>
                     </tr>
                   )
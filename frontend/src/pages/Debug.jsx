@@ -24,19 +24,19 @@ export default function Debug() {
             {JSON.stringify(data.settings, null, 2)}
           </pre>
           <p className="mt-4 text-cyan-400">
-            Ищи здесь: <strong>eula_room_filter</strong> — должен быть ID кабинета (например "1", "203")
+            Кабинеты для таблицы EULA здесь больше не хранятся — они передаются в запросе (<strong>?room=1&amp;room=2</strong>)
           </p>
         </div>
 
         <div>
-          <h2 className="text-2xl text-yellow-400 mb-4">2. Рабочие места (workplaces_config)</h2>
+          <h2 className="text-2xl text-yellow-400 mb-4">2. Рабочие места (rooms, devices)</h2>
           <pre className="bg-black p-6 rounded-lg overflow-auto max-h-96">
             {JSON.stringify(data.workplaces, null, 2)}
           </pre>
           <p className="mt-4 text-cyan-400">
             Проверь: <br/>
-            • Есть ли <strong>rooms</strong> с форматом "ID:Название"? <br/>
-            • У устройств <strong>roomId</strong> — число или строка?
+            • У каждого кабинета в <strong>rooms</strong> есть id? <br/>
+            • <strong>roomId</strong> устройств совпадает с id кабинета?
           </p>
         </div>
       </div>
@@ -4,24 +4,60 @@ import { useState, useEffect } from 'react'
 export default function DocsPage() {
   const [activeDoc, setActiveDoc] = useState('eula')
   const [refreshKey, setRefreshKey] = useState(Date.now())
+  const [versions, setVersions] = useState([])
+  const [version, setVersion] = useState('') // '' — действующая редакция
+  const [lang, setLang] = useState('ru')
+  const [rooms, setRooms] = useState([])
+  // Условия таблицы рабочих мест: уходят параметрами в /api/docs/eula-table
+  const [tableRooms, setTableRooms] = useState([]) // id кабинетов, 'none' — вне кабинетов
+  const [tableType, setTableType] = useState('')
+  const [tableStatus, setTableStatus] = useState('')
 
-  // Обновляем refreshKey при смене документа
+  // Обновляем refreshKey при смене документа или языка
   useEffect(() => {
     setRefreshKey(Date.now())
-  }, [activeDoc])
+    setVersion('')
+  }, [activeDoc, lang])
 
   // Всегда свежий URL с текущим refreshKey
-  const getUrl = (path) => `/api/docs/${path}?t=${refreshKey}`
+  const getUrl = (path, v = '') => `/api/docs/${path}?t=${refreshKey}&lang=${lang}${v ? `&version=${v}` : ''}`
+
+  const tableUrl = (format = '') => {
+    const params = new URLSearchParams({ lang })
+    tableRooms.forEach(id => params.append('room', id))
+    if (tableType) params.set('type', tableType)
+    if (tableStatus) params.set('status', tableStatus)
+    if (format) params.set('format', format)
+    return `/api/docs/eula-table?${params}`
+  }
+
+  useEffect(() => {
+    fetch('/api/workplaces')
+      .then(r => r.json())
+      .then(wp => setRooms(wp.rooms || []))
+      .catch(() => {})
+  }, [])
+
+  const toggleTableRoom = (id) =>
+    setTableRooms(prev => prev.includes(id) ? prev.filter(x => x !== id) : [...prev, id])
 
   const docs = [
-    { id: 'eula',     title: 'Лицензионное соглашение',     path: 'eula' },
-    { id: 'privacy',  title: 'Политика конфиденциальности',path: 'privacy' },
-    { id: 'offer',    title: 'Публичная оферта',           path: 'offer' },
-    { id: 'payment',  title: 'Платёжное поручение',        path: 'payment' },
-    { id: 'invoice',  title: 'Товарная накладная',         path: 'invoice' },
+    { id: 'eula',     title: 'Лицензионное соглашение',     path: 'eula',    key: 'eula_text' },
+    { id: 'privacy',  title: 'Политика конфиденциальности',path: 'privacy', key: 'privacy_policy' },
+    { id: 'offer',    title: 'Публичная оферта',           path: 'offer',   key: 'offer_text' },
+    { id: 'payment',  title: 'Платёжное поручение',        path: 'payment', key: 'payment_text' },
+    { id: 'invoice',  title: 'Товарная накладная',         path: 'invoice', key: 'invoice_text' },
   ]
 
   const currentDoc = docs.find(d => d.id === activeDoc)
+
+  // История редакций текущего документа на выбранном языке
+  useEffect(() => {
+    fetch(`/api/documents/${currentDoc.key}/versions?lang=${lang}`)
+      .then(r => r.ok ? r.json() : [])
+      .then(setVersions)
+      .catch(() => setVersions([]))
+  }, [currentDoc.key, lang, refreshKey])
   const isEula = activeDoc === 'eula'
 
   return (
@@ -54,11 +90,47 @@ export default function DocsPage() {
           ))}
         </div>
 
+        {/* Язык, редакция и PDF для бухгалтерии */}
+        <div className="flex flex-wrap items-center justify-between gap-4 mb-4">
+          <div className="flex gap-2">
+            {['ru', 'en'].map(l => (
+              <button
+                key={l}
+                onClick={() => setLang(l)}
+                className={`px-5 py-3 rounded-full font-bold border-2 border-indigo-300
+                  ${lang === l ? 'bg-indigo-600 text-white' : 'bg-white text-indigo-700'}`}
+              >
+                {l.toUpperCase()}
+              </button>
+            ))}
+          </div>
+          <select
+            value={version}
+            onChange={e => setVersion(e.target.value)}
+            className="px-6 py-3 bg-white border-2 border-indigo-300 rounded-full font-bold text-indigo-700"
+          >
+            <option value="">Действующая редакция</option>
+            {versions.filter(v => v.status === 'published').map(v => (
+              <option key={v.version} value={v.version}>
+                Редакция {v.version} от {new Date(v.effective_from).toLocaleDateString('ru-RU')}{v.current ? ' (действует)' : ''}
+              </option>
+            ))}
+          </select>
+          <a
+            href={`/api/docs/${currentDoc.path}?format=pdf&lang=${lang}${version ? `&version=${version}` : ''}`}
+            target="_blank"
+            rel="noreferrer"
+            className="inline-block px-8 py-3 bg-white border-2 border-indigo-300 text-indigo-700 font-bold rounded-full shadow hover:shadow-lg transition"
+          >
+            Скачать PDF
+          </a>
+        </div>
+
         {/* Главный документ — всегда свежий URL */}
         <div className="bg-white rounded-3xl shadow-2xl overflow-hidden border-4 border-gray-200 mb-12" style={{ height: '1000px' }}>
           <iframe
             key={refreshKey}  // ← КЛЮЧЕВОЕ ИСПРАВЛЕНИЕ: перерисовка iframe при обновлении
-            src={getUrl(currentDoc.path)}
+            src={getUrl(currentDoc.path, version)}
             className="w-full h-full border-0"
             title={currentDoc.title}
             sandbox="allow-same-origin allow-popups allow-scripts"
@@ -66,15 +138,70 @@ export default function DocsPage() {
           />
         </div>
 
+        {/* История редакций */}
+        {versions.length > 0 && (
+          <div className="bg-white rounded-3xl shadow-xl p-8 border-2 border-gray-200 mb-12">
+            <h2 className="text-2xl font-black mb-4 text-indigo-700">История редакций</h2>
+            <table className="w-full text-left">
+              <thead>
+                <tr className="text-gray-500 text-sm">
+                  <th className="py-2">№</th><th>Статус</th><th>Вступает в силу</th><th>Автор</th><th>Комментарий</th><th></th>
+                </tr>
+              </thead>
+              <tbody>
+                {versions.map((v, i) => (
+                  <tr key={v.version} className="border-t">
+                    <td className="py-2 font-bold">{v.version}</td>
+                    <td>{v.status === 'draft' ? 'Черновик' : v.current ? 'Действует' : 'Опубликована'}</td>
+                    <td>{v.effective_from ? new Date(v.effective_from).toLocaleDateString('ru-RU') : '—'}</td>
+                    <td>{v.author}</td>
+                    <td className="text-gray-600">{v.comment || ''}</td>
+                    <td className="space-x-3 text-sm">
+                      <a href={`/api/documents/${currentDoc.key}/versions/${v.version}/preview`} target="_blank" rel="noreferrer" className="text-indigo-600 hover:underline">Просмотр</a>
+                      {i < versions.length - 1 && (
+                        <a href={`/api/documents/${currentDoc.key}/diff?from=${versions[i + 1].version}&to=${v.version}&format=text`} target="_blank" rel="noreferrer" className="text-indigo-600 hover:underline">Изменения</a>
+                      )}
+                    </td>
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </div>
+        )}
+
         {/* Таблица только для EULA */}
         {isEula && (
           <div className="bg-white rounded-3xl shadow-2xl p-10 border-4 border-indigo-300">
             <h2 className="text-4xl font-black text-center mb-8 text-indigo-700">
               Активные лицензии (на момент формирования)
             </h2>
+            <div className="flex flex-wrap items-center gap-3 mb-6">
+              {[...rooms.map(r => ({ id: String(r.id), name: r.name })), { id: 'none', name: 'Вне кабинетов' }].map(r => (
+                <button key={r.id} onClick={() => toggleTableRoom(r.id)}
+                  className={`px-4 py-2 rounded-xl border-2 font-bold ${tableRooms.includes(r.id) ? 'bg-indigo-600 text-white border-indigo-700' : 'bg-white border-indigo-300 text-indigo-800'}`}>
+                  {r.name}
+                </button>
+              ))}
+              <select value={tableType} onChange={e => setTableType(e.target.value)} className="px-4 py-2 rounded-xl border-2 border-indigo-300">
+                <option value="">Все типы</option>
+                <option value="desktop">ПК</option>
+                <option value="laptop">Ноутбук</option>
+                <option value="tablet">Планшет</option>
+                <option value="server">Сервер</option>
+              </select>
+              <select value={tableStatus} onChange={e => setTableStatus(e.target.value)} className="px-4 py-2 rounded-xl border-2 border-indigo-300">
+                <option value="">Любой статус</option>
+                <option value="active">Активен</option>
+                <option value="expired">Истёк</option>
+                <option value="unknown">Без лицензий</option>
+              </select>
+              <a href={tableUrl('pdf')} target="_blank" rel="noreferrer" className="text-indigo-600 font-bold hover:underline">PDF</a>
+              <a href={tableUrl('xlsx')} className="text-indigo-600 font-bold hover:underline">XLSX</a>
+            </div>
+            <p className="text-gray-500 text-sm mb-4">Без выбранных кабинетов — все устройства</p>
             <iframe
               key={`table-${refreshKey}`}  // ← И здесь тоже key, чтобы обновлялось
-              src={getUrl('eula-table')}
+              src={`${tableUrl()}&t=${refreshKey}`}
               className="w-full border-0 rounded-2xl shadow-inner"
               style={{ height: '600px' }}
               title="Таблица активных рабочих мест"
@@ -93,7 +220,7 @@ export default function DocsPage() {
             Обновить документы и таблицу
           </button>
           <p className="text-gray-500 mt-4 text-sm">
-            Используется при смене кабинета в настройках
+            Подтягивает свежие тексты и состояние рабочих мест
           </p>
         </div>
 
@@ -39,8 +39,8 @@ export default function ExportPage() {
     }
   }
 
-  const download = (format) => {
-    window.location.href = `/api/licenses/export?format=${format}`
+  const download = (format, params = '') => {
+    window.location.href = `/api/licenses/export?format=${format}${params}`
   }
 
   return (
@@ -58,6 +58,9 @@ export default function ExportPage() {
               <button onClick={() => download('csv')} className="w-full bg-gradient-to-r from-green-500 to-emerald-600 hover:from-green-600 hover:to-emerald-700 text-white font-bold text-3xl py-12 rounded-3xl shadow-2xl transition transform hover:scale-105">
                 Скачать CSV
               </button>
+              <button onClick={() => download('csv', '&delimiter=semicolon&bom=1')} className="w-full bg-gradient-to-r from-teal-500 to-green-600 hover:from-teal-600 hover:to-green-700 text-white font-bold text-3xl py-12 rounded-3xl shadow-2xl transition transform hover:scale-105">
+                CSV для русского Excel
+              </button>
               <button onClick={() => download('xlsx')} className="w-full bg-gradient-to-r from-orange-500 to-red-600 hover:from-orange-600 hover:to-red-700 text-white font-bold text-3xl py-12 rounded-3xl shadow-2xl transition transform hover:scale-105">
                 Скачать Excel (.xlsx)
               </button>
@@ -5,24 +5,13 @@ import styles from '../components/Licensemanager.module.css'
 export default function SettingsPage() {
   const [activeTab, setActiveTab] = useState('company')
   const [form, setForm] = useState({})
-  const [rooms, setRooms] = useState([]) // [{index: 0, name: "Кабинет 101"}]
   const [isSaving, setIsSaving] = useState(false)
 
   // Загружаем ВСЁ при открытии
   useEffect(() => {
-    Promise.all([
-      fetch('/api/settings').then(r => r.json()),
-      fetch('/api/workplaces').then(r => r.json())
-    ]).then(([settings, wp]) => {
-      setForm(settings)
-
-      // Формируем список кабинетов с индексами
-      const roomList = (wp.rooms || []).map((name, idx) => ({
-        index: idx,
-        name: name
-      }))
-      setRooms(roomList)
-    })
+    fetch('/api/settings')
+      .then(r => r.json())
+      .then(setForm)
   }, [])
 
   const save = () => {
@@ -31,10 +20,17 @@ export default function SettingsPage() {
       method: 'POST',
       headers: { 'Content-Type': 'application/json' },
       body: JSON.stringify(form)
-    }).finally(() => setTimeout(() => {
-      setIsSaving(false)
-      alert('Настройки сохранены!')
-    }, 3800))
+    }).then(async res => {
+      if (!res.ok) {
+        setIsSaving(false)
+        alert(await res.text())
+        return
+      }
+      setTimeout(() => {
+        setIsSaving(false)
+        alert('Настройки сохранены!')
+      }, 3800)
+    }).catch(() => setIsSaving(false))
   }
 
   const update = (k, v) => setForm(p => ({ ...p, [k]: v }))
@@ -76,30 +72,6 @@ export default function SettingsPage() {
                     className="w-full px-6 py-5 rounded-2xl border-2 border-gray-300 focus:border-indigo-600 outline-none text-lg" />
                 </div>
               ))}
-
-              {/* ВЫБОР КАБИНЕТА ДЛЯ EULA */}
-              <div className="bg-gradient-to-r from-indigo-50 to-purple-50 p-10 rounded-3xl border-4 border-indigo-300">
-                <label className="block text-2xl font-black text-indigo-900 mb-6">
-                  Кабинет для таблицы в Лицензионном соглашении
-                </label>
-                <select
-                  value={form.eula_room_filter || ''}
-                  onChange={e => update('eula_room_filter', e.target.value)}
-                  className="w-full px-8 py-6 rounded-2xl border-4 border-indigo-700 bg-white text-xl font-bold"
-                >
-                  <option value="">Все кабинеты</option>
-                  {rooms.map(room => (
-                    <option key={room.index} value={room.index}>
-                      {room.name}
-                    </option>
-                  ))}
-                </select>
-                <p className="text-lg text-indigo-800 mt-4">
-                  Сейчас: <strong>
-                    {form.eula_room_filter === '' ? 'Все кабинеты' : rooms.find(r => r.index == form.eula_room_filter)?.name || '—'}
-                  </strong>
-                </p>
-              </div>
             </>
           )}
 
@@ -3,62 +3,127 @@ import React, { useState, useEffect, useRef } from 'react'
 
 export default function Workplaces() {
   const [devices, setDevices] = useState([])
-  const [rooms, setRooms] = useState([]) // [{index: 0, name: "..."}]
-  const [selectedRoomIndex, setSelectedRoomIndex] = useState(null)
+  const [rooms, setRooms] = useState([]) // [{id: 1, name: "..."}]
+  const [selectedRoomId, setSelectedRoomId] = useState(null)
   const [newRoomName, setNewRoomName] = useState('')
   const [scale, setScale] = useState(1)
+  const [conflicts, setConflicts] = useState(null) // отчёт /api/workplaces/conflicts
+  const [discovered, setDiscovered] = useState([]) // очередь /api/workplaces/discovery
+  const [checked, setChecked] = useState([])
   const containerRef = useRef(null)
 
-  // === ЗАГРУЗКА + АВТОВЫБОР ПЕРВОГО КАБИНЕТА ===
-  useEffect(() => {
+  // Каждое действие сразу сохраняется своим запросом к /api/rooms и
+  // /api/devices с версией записи; если её успел изменить кто-то другой,
+  // сервер отвечает 409 — показываем сообщение и перечитываем схему
+  const normalizeDevice = d => ({
+    ...d,
+    name: d.name || '',
+    mac: d.mac || '',
+    status: d.status || null, // вычисляет сервер по привязанным лицензиям
+    licenseIds: d.licenseIds || [],
+    x: Number(d.x) || 100,
+    y: Number(d.y) || 100,
+    roomId: d.roomId != null ? Number(d.roomId) : null,
+    type: d.type || 'desktop',
+  })
+
+  const load = () =>
     fetch('/api/workplaces')
       .then(r => r.json())
       .then(data => {
-        const loadedRooms = (data.rooms || []).map((name, index) => ({
-          index,
-          name
-        }))
-        setRooms(loadedRooms)
-
-        const safeDevices = (data.devices || []).map(d => ({
-          ...d,
-          mac: d.mac || '',
-          status: d.status || null,
-          x: Number(d.x) || 100,
-          y: Number(d.y) || 100,
-          roomId: d.roomId != null ? Number(d.roomId) : null,
-          type: d.type || 'desktop',
-        }))
-        setDevices(safeDevices)
+        setRooms(data.rooms || [])
+        setDevices((data.devices || []).map(normalizeDevice))
+        return data.rooms || []
+      })
+
+  // Запрос с телом JSON; при ошибке — сообщение сервера и перезагрузка схемы
+  const api = (url, method, body) =>
+    fetch(url, {
+      method,
+      headers: { 'Content-Type': 'application/json' },
+      body: body ? JSON.stringify(body) : undefined
+    }).then(async res => {
+      if (!res.ok) {
+        alert(await res.text())
+        if (res.status === 409 || res.status === 404) load()
+        return null
+      }
+      return method === 'DELETE' ? true : res.json()
+    }).catch(() => {
+      alert('Ошибка сети')
+      return null
+    })
+
+  const replaceDevice = saved => {
+    if (saved) setDevices(prev => prev.map(d => d.id === saved.id ? normalizeDevice(saved) : d))
+  }
 
+  const updateDevice = (id, fields) => {
+    const device = devices.find(d => d.id === id)
+    return api(`/api/devices/${id}`, 'PUT', { version: device.version, ...fields }).then(replaceDevice)
+  }
+
+  // === ЗАГРУЗКА + АВТОВЫБОР ПЕРВОГО КАБИНЕТА ===
+  useEffect(() => {
+    load()
+      .then(loadedRooms => {
         // АВТОВЫБОР ПЕРВОГО КАБИНЕТА ПРИ ЗАГРУЗКЕ
-        if (loadedRooms.length > 0 && selectedRoomIndex === null) {
-          setSelectedRoomIndex(loadedRooms[0].index)
+        if (loadedRooms.length > 0 && selectedRoomId === null) {
+          setSelectedRoomId(loadedRooms[0].id)
         }
       })
       .catch(() => {})
   }, [])
 
+  // MAC проверяет и приводит к виду 00:1A:2B:3C:4D:5E сервер; отчёт о
+  // совпадениях перечитываем после каждого изменения устройств
+  useEffect(() => {
+    fetch('/api/workplaces/conflicts')
+      .then(r => r.json())
+      .then(setConflicts)
+      .catch(() => {})
+  }, [devices])
+
+  // Устройства, найденные в DHCP/ARP и ещё не разобранные
+  const loadDiscovered = () =>
+    fetch('/api/workplaces/discovery')
+      .then(r => r.json())
+      .then(list => {
+        setDiscovered(list)
+        setChecked(prev => prev.filter(id => list.some(d => d.id === id)))
+      })
+      .catch(() => {})
+
+  useEffect(() => { loadDiscovered() }, [])
+
   // Если кабинеты изменились (например, удалили текущий) — выбираем первый
   useEffect(() => {
-    if (rooms.length > 0 && (selectedRoomIndex === null || !rooms.find(r => r.index === selectedRoomIndex))) {
-      setSelectedRoomIndex(rooms[0].index)
+    if (rooms.length > 0 && (selectedRoomId === null || !rooms.find(r => r.id === selectedRoomId))) {
+      setSelectedRoomId(rooms[0].id)
     }
-  }, [rooms, selectedRoomIndex])
+  }, [rooms, selectedRoomId])
 
-  const visibleDevices = selectedRoomIndex === null
+  const visibleDevices = selectedRoomId === null
     ? devices
-    : devices.filter(d => d.roomId === selectedRoomIndex)
+    : devices.filter(d => d.roomId === selectedRoomId)
 
   const createRoom = () => {
     if (!newRoomName.trim()) return
-    const newRoom = {
-      index: rooms.length,
-      name: newRoomName.trim()
-    }
-    setRooms(prev => [...prev, newRoom])
-    setSelectedRoomIndex(newRoom.index)
-    setNewRoomName('')
+    api('/api/rooms', 'POST', { name: newRoomName.trim() }).then(room => {
+      if (!room) return
+      setRooms(prev => [...prev, room])
+      setSelectedRoomId(room.id)
+      setNewRoomName('')
+    })
+  }
+
+  const renameRoom = (id) => {
+    const room = rooms.find(r => r.id === id)
+    const name = prompt('Новое название кабинета:', room.name)
+    if (!name || !name.trim() || name.trim() === room.name) return
+    api(`/api/rooms/${id}`, 'PUT', { version: room.version, name: name.trim() }).then(saved => {
+      if (saved) setRooms(prev => prev.map(r => r.id === id ? saved : r))
+    })
   }
 
   const handleDropNew = (e) => {
@@ -73,19 +138,16 @@ export default function Workplaces() {
     y = Math.round(y / 100) * 100
 
     const mac = prompt('Введите MAC-адрес устройства:', '00:1A:2B:3C:4D:5E')
-    if (!mac || !/^([0-9A-F]{2}[:-]){5}[0-9A-F]{2}$/i.test(mac.trim())) {
-      alert('Неверный MAC!')
-      return
-    }
+    if (mac === null) return
 
-    setDevices(prev => [...prev, {
-      id: Date.now(),
-      mac: mac.trim().toUpperCase(),
+    api('/api/devices', 'POST', {
+      mac: mac.trim(),
       type,
       x, y,
-      roomId: selectedRoomIndex,
-      status: null
-    }])
+      roomId: selectedRoomId
+    }).then(saved => {
+      if (saved) setDevices(prev => [...prev, normalizeDevice(saved)])
+    })
   }
 
   const handleDeviceMove = (id, e) => {
@@ -95,58 +157,82 @@ export default function Workplaces() {
     x = Math.round(x / 100) * 100
     y = Math.round(y / 100) * 100
 
+    // Сразу двигаем на экране, ответ сервера подтвердит или откатит
     setDevices(prev => prev.map(d =>
       d.id === id ? { ...d, x, y } : d
     ))
+    updateDevice(id, { x, y })
   }
 
   const editMac = (id) => {
     const device = devices.find(d => d.id === id)
     const newMac = prompt('Изменить MAC-адрес:', device.mac)
-    if (!newMac) return
-    if (!/^([0-9A-F]{2}[:-]){5}[0-9A-F]{2}$/i.test(newMac.trim())) {
-      alert('Неверный формат!')
-      return
-    }
-    setDevices(prev => prev.map(d =>
-      d.id === id ? { ...d, mac: newMac.trim().toUpperCase() } : d
-    ))
+    if (newMac === null) return
+    updateDevice(id, { mac: newMac.trim() })
+  }
+
+  const renameDevice = (id) => {
+    const device = devices.find(d => d.id === id)
+    const name = prompt('Сетевое имя устройства (например, PC-IVANOV):', device.name)
+    if (name === null || name.trim() === device.name) return
+    updateDevice(id, { name: name.trim() })
   }
 
   const deleteDevice = (id) => {
-    if (confirm('Удалить устройство?')) {
-      setDevices(prev => prev.filter(d => d.id !== id))
-    }
+    if (!confirm('Удалить устройство?')) return
+    const device = devices.find(d => d.id === id)
+    api(`/api/devices/${id}?version=${device.version}`, 'DELETE').then(ok => {
+      if (ok) setDevices(prev => prev.filter(d => d.id !== id))
+    })
   }
 
-  const checkKey = (id) => {
+  // Привязка лицензии к устройству: статус сервер пересчитывает сам
+  const linkKey = (id) => {
     const key = prompt('Вставьте ключ:')
     if (!key) return
-
-    fetch('/api/licenses/validate', {
-      method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({ key: key.trim().toUpperCase() })
+    api(`/api/devices/${id}/licenses`, 'POST', { key: key.trim().toUpperCase() }).then(saved => {
+      if (!saved) return
+      replaceDevice(saved)
+      alert(saved.status === 'active' ? 'Ключ привязан, лицензия действует' : 'Ключ привязан, но срок лицензии истёк')
     })
-      .then(r => r.json())
-      .then(res => {
-        setDevices(prev => prev.map(d =>
-          d.id === id ? { ...d, status: res.valid ? 'active' : 'expired' } : d
-        ))
-        alert(res.valid ? 'Активен!' : 'Недействителен')
-      })
-      .catch(() => alert('Ошибка'))
   }
 
-  const save = () => {
-    fetch('/api/workplaces', {
-      method: 'POST',
-      headers: { 'Content-Type': 'application/json' },
-      body: JSON.stringify({
-        rooms: rooms.map(r => r.name),
-        devices: devices
+  // Файл аренд DHCP или вывод arp -a / ip neigh: формат сервер определит сам
+  const uploadDiscovery = (e) => {
+    const file = e.target.files[0]
+    e.target.value = ''
+    if (!file) return
+    const form = new FormData()
+    form.append('file', file)
+    fetch('/api/workplaces/discovery', { method: 'POST', body: form })
+      .then(async res => {
+        if (!res.ok) {
+          alert(await res.text())
+          return
+        }
+        const result = await res.json()
+        alert(`Найдено устройств: ${result.total}\nУже на схеме: ${result.matched.length}\nНовых: ${result.proposed.length}` +
+          (result.dismissed ? `\nСкрытых ранее: ${result.dismissed}` : '') +
+          (result.skipped.length ? `\nПропущено строк: ${result.skipped.length}` : ''))
+        loadDiscovered()
       })
-    }).then(() => alert('Сохранено!'))
+      .catch(() => alert('Ошибка сети'))
+  }
+
+  const toggleChecked = (id) =>
+    setChecked(prev => prev.includes(id) ? prev.filter(x => x !== id) : [...prev, id])
+
+  const approveDiscovered = () => {
+    if (checked.length === 0 || selectedRoomId === null) return
+    api('/api/workplaces/discovery/approve', 'POST', { ids: checked, roomId: selectedRoomId }).then(created => {
+      loadDiscovered()
+      if (created) setDevices(prev => [...prev, ...created.map(normalizeDevice)])
+    })
+  }
+
+  const dismissDiscovered = () => {
+    if (checked.length === 0) return
+    api('/api/workplaces/discovery/dismiss', 'POST', { ids: checked }).then(loadDiscovered)
   }
 
   const handleWheel = (e) => {
@@ -157,15 +243,19 @@ export default function Workplaces() {
   }
 
   // УДАЛЕНИЕ КАБИНЕТА — БЕЗ СЪЕЗДА!
-  const deleteRoom = (indexToDelete) => {
-    if (!confirm(`Удалить "${rooms.find(r => r.index === indexToDelete)?.name}" и все устройства в нём?`)) return
-
-    setRooms(prev => prev.filter(r => r.index !== indexToDelete))
-    setDevices(prev => prev.filter(d => d.roomId !== indexToDelete))
-
-    if (selectedRoomIndex === indexToDelete) {
-      setSelectedRoomIndex(rooms.length > 1 ? rooms[0].index : null)
-    }
+  const deleteRoom = (idToDelete) => {
+    const room = rooms.find(r => r.id === idToDelete)
+    if (!confirm(`Удалить "${room?.name}" и все устройства в нём?`)) return
+
+    api(`/api/rooms/${idToDelete}?version=${room.version}`, 'DELETE').then(ok => {
+      if (!ok) return
+      setRooms(prev => prev.filter(r => r.id !== idToDelete))
+      setDevices(prev => prev.filter(d => d.roomId !== idToDelete))
+
+      if (selectedRoomId === idToDelete) {
+        setSelectedRoomId(null)
+      }
+    })
   }
 
   return (
@@ -221,20 +311,25 @@ export default function Workplaces() {
                         <div className="flex justify-center mb-4">
                           <img src={iconPath} alt="device" className="w-32 h-32 object-contain drop-shadow-2xl" draggable={false} />
                         </div>
+                        <div onDoubleClick={() => renameDevice(d.id)} title="Двойной клик — переименовать"
+                          className="text-center text-gray-800 text-2xl font-bold mb-2 truncate cursor-text">
+                          {d.name || 'Без имени'}
+                        </div>
                         <div className="font-mono text-center text-purple-800 text-xl font-black tracking-wider break-all bg-gray-100 py-3 px-4 rounded-xl">
                           {d.mac || 'MAC не задан'}
                         </div>
                         {d.status && (
                           <div className={`text-center mt-4 font-bold text-xl px-6 py-3 rounded-xl ${d.status === 'active' ? 'bg-green-100 text-green-700 border-2 border-green-500' : 'bg-red-100 text-red-700 border-2 border-red-500'}`}>
                             {d.status === 'active' ? 'Активен' : 'Истёк'}
+                            <span className="block text-sm font-normal">Лицензий: {d.licenseIds.length}</span>
                           </div>
                         )}
                         <div className="grid grid-cols-2 gap-4 mt-6">
                           <button onClick={() => editMac(d.id)} className="bg-blue-600 hover:bg-blue-700 text-white font-bold py-3 rounded-2xl text-lg">Изменить</button>
                           <button onClick={() => deleteDevice(d.id)} className="bg-red-600 hover:bg-red-700 text-white font-bold py-3 rounded-2xl text-lg">Удалить</button>
                         </div>
-                        <button onClick={() => checkKey(d.id)} className="mt-4 w-full bg-gradient-to-r from-indigo-600 to-purple-700 hover:from-indigo-700 hover:to-purple-800 text-white font-black py-4 rounded-2xl text-xl">
-                          Проверить ключ
+                        <button onClick={() => linkKey(d.id)} className="mt-4 w-full bg-gradient-to-r from-indigo-600 to-purple-700 hover:from-indigo-700 hover:to-purple-800 text-white font-black py-4 rounded-2xl text-xl">
+                          Привязать ключ
                         </button>
                       </div>
                     )
@@ -242,7 +337,7 @@ export default function Workplaces() {
 
                   {visibleDevices.length === 0 && (
                     <div className="absolute inset-0 flex items-center justify-center text-5xl text-gray-300 pointer-events-none font-light">
-                      {selectedRoomIndex !== null ? 'Перетащите устройства сюда' : 'Выберите или создайте кабинет'}
+                      {selectedRoomId !== null ? 'Перетащите устройства сюда' : 'Выберите или создайте кабинет'}
                     </div>
                   )}
                 </div>
@@ -277,23 +372,25 @@ export default function Workplaces() {
 
             <div className="space-y-4">
               {rooms.map(room => (
-                <div key={room.index} className="flex items-center gap-3 group">
+                <div key={room.id} className="flex items-center gap-3 group">
                   <button
-                    onClick={() => setSelectedRoomIndex(room.index)}
+                    onClick={() => setSelectedRoomId(room.id)}
+                    onDoubleClick={() => renameRoom(room.id)}
+                    title="Двойной щелчок — переименовать"
                     className={`flex-1 text-left p-6 rounded-2xl border-4 transition-all ${
-                      selectedRoomIndex === room.index
+                      selectedRoomId === room.id
                         ? 'bg-gradient-to-r from-purple-600 to-pink-600 text-white border-purple-800 shadow-2xl'
                         : 'bg-purple-50 border-purple-300 hover:border-purple-500'
                     }`}
                   >
                     <div className="font-bold text-xl">{room.name}</div>
                     <div className="text-sm opacity-80 mt-1">
-                      {devices.filter(d => d.roomId === room.index).length} устройств
+                      {devices.filter(d => d.roomId === room.id).length} устройств
                     </div>
                   </button>
 
                   <button
-                    onClick={() => deleteRoom(room.index)}
+                    onClick={() => deleteRoom(room.id)}
                     className="opacity-0 group-hover:opacity-100 p-3 bg-red-500 hover:bg-red-600 text-white rounded-xl shadow-lg transition-all duration-200 transform hover:scale-110"
                     title="Удалить кабинет"
                   >
@@ -304,14 +401,63 @@ export default function Workplaces() {
                 </div>
               ))}
             </div>
+
+            {conflicts && (conflicts.duplicates.length + conflicts.licenseConflicts.length + conflicts.invalid.length) > 0 && (
+              <div className="mt-8 p-5 bg-amber-50 border-2 border-amber-400 rounded-2xl text-sm space-y-3">
+                <h3 className="text-xl font-bold text-amber-800">Конфликты MAC</h3>
+                {conflicts.duplicates.map(c => (
+                  <div key={'dup' + c.mac}>
+                    <span className="font-mono font-bold">{c.mac}</span> — у {c.devices.length} устройств ({c.rooms.join(', ')})
+                  </div>
+                ))}
+                {conflicts.licenseConflicts.map(c => (
+                  <div key={'lic' + c.mac}>
+                    <span className="font-mono font-bold">{c.mac}</span> — лицензии: {c.licenses.map(l => l.product ? `${l.key} (${l.product})` : l.key).join(', ')}
+                  </div>
+                ))}
+                {conflicts.invalid.map(d => (
+                  <div key={'bad' + d.id}>
+                    Устройство {d.name || d.id} ({d.room}): некорректный MAC <span className="font-mono font-bold">{d.mac}</span>
+                  </div>
+                ))}
+              </div>
+            )}
+
+            <div className="mt-8 p-5 bg-indigo-50 border-2 border-indigo-300 rounded-2xl text-sm space-y-3">
+              <h3 className="text-xl font-bold text-indigo-800">Найдено в сети</h3>
+              <label className="block w-full text-center bg-indigo-600 hover:bg-indigo-700 text-white font-bold py-3 rounded-xl cursor-pointer">
+                Загрузить DHCP / ARP
+                <input type="file" className="hidden" onChange={uploadDiscovery} />
+              </label>
+              {discovered.map(d => (
+                <label key={d.id} className="flex items-start gap-2 cursor-pointer">
+                  <input type="checkbox" className="mt-1" checked={checked.includes(d.id)} onChange={() => toggleChecked(d.id)} />
+                  <span>
+                    <span className="font-bold">{d.hostname || 'Без имени'}</span>{' '}
+                    <span className="font-mono">{d.mac}</span>
+                    <span className="block text-gray-500">{[d.vendor, d.ip].filter(Boolean).join(', ')}</span>
+                  </span>
+                </label>
+              ))}
+              {discovered.length > 0 && (
+                <div className="grid grid-cols-2 gap-3">
+                  <button onClick={approveDiscovered} disabled={checked.length === 0 || selectedRoomId === null}
+                    className="bg-green-600 hover:bg-green-700 disabled:opacity-50 text-white font-bold py-2 rounded-xl">
+                    В кабинет
+                  </button>
+                  <button onClick={dismissDiscovered} disabled={checked.length === 0}
+                    className="bg-gray-500 hover:bg-gray-600 disabled:opacity-50 text-white font-bold py-2 rounded-xl">
+                    Скрыть
+                  </button>
+                </div>
+              )}
+            </div>
           </div>
         </div>
 
-        <div className="text-center mt-12">
-          <button onClick={save} className="px-16 md:px-24 py-6 md:py-8 bg-gradient-to-r from-indigo-600 to-purple-600 text-white text-3xl md:text-4xl font-black rounded-full shadow-3xl hover:shadow-4xl transform hover:scale-110 transition-all">
-            Сохранить схему навсегда
-          </button>
-        </div>
+        <p className="text-center mt-12 text-lg text-gray-500">
+          Изменения сохраняются сразу
+        </p>
       </div>
     </div>
   )